@@ -19,6 +19,12 @@ var (
 	configDir      string
 	configInterval string
 	configRefresh  string
+	configFrom     string
+	configTo       string
+	configTimezone string
+	concurrency    int
+	alertsExec     string
+	alertsBell     bool
 	debug          bool
 	query          string
 )
@@ -44,7 +50,7 @@ var rootCmd = &cobra.Command{
 			log.Fatalf("Could not load dashboards: %v", err)
 		}
 
-		err = render.Run(false, datasources, dashboards, configInterval, configRefresh)
+		err = render.Run(false, datasources, dashboards, getInterval(), configRefresh, configTimezone, concurrency, alertsExec, alertsBell)
 		if err != nil {
 			log.Fatalf("Unexpected error: %v", err)
 		}
@@ -72,7 +78,7 @@ var exploreCmd = &cobra.Command{
 			log.Fatalf("Could not create explore dashboard: %v", err)
 		}
 
-		err = render.Run(true, datasources, dashboards, configInterval, configRefresh)
+		err = render.Run(true, datasources, dashboards, getInterval(), configRefresh, configTimezone, concurrency, alertsExec, alertsBell)
 		if err != nil {
 			log.Fatalf("Unexpected error: %v", err)
 		}
@@ -94,6 +100,16 @@ var versionCmd = &cobra.Command{
 	},
 }
 
+// getInterval returns the initial interval. If a start time was provided via the config.from flag, the interval is the
+// time range between the provided start and end time, otherwise the value of the config.interval flag is used.
+func getInterval() string {
+	if configFrom != "" {
+		return configFrom + " to " + configTo
+	}
+
+	return configInterval
+}
+
 func init() {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
@@ -104,7 +120,13 @@ func init() {
 
 	rootCmd.PersistentFlags().StringVar(&configDir, "config.dir", dashPath, "Location of the datasources and dashboards folder.")
 	rootCmd.PersistentFlags().StringVar(&configInterval, "config.interval", "1h", "Interval to retrieve data for.")
+	rootCmd.PersistentFlags().StringVar(&configFrom, "config.from", "", "Start of the time range, e.g. \"now-90m\" or \"2020-10-01 14:00\". Overwrites the interval.")
+	rootCmd.PersistentFlags().StringVar(&configTo, "config.to", "now", "End of the time range, e.g. \"now\" or \"2020-10-01 16:30\". Only used together with config.from.")
+	rootCmd.PersistentFlags().StringVar(&configTimezone, "config.timezone", "", "Timezone for the shown and entered times, e.g. \"UTC\", \"local\" or \"Europe/Berlin\". Overwrites the timezone of the dashboards.")
 	rootCmd.PersistentFlags().StringVar(&configRefresh, "config.refresh", "5m", "Time between refreshs of the dashboard.")
+	rootCmd.PersistentFlags().IntVar(&concurrency, "config.concurrency", 4, "Number of graphs which are loaded concurrently.")
+	rootCmd.PersistentFlags().StringVar(&alertsExec, "alerts.exec", "", "Command which is run when an alert starts firing. The alert is passed via the DASH_ALERT_* environment variables.")
+	rootCmd.PersistentFlags().BoolVar(&alertsBell, "alerts.bell", false, "Ring the terminal bell when an alert starts firing.")
 	rootCmd.PersistentFlags().BoolVar(&debug, "debug", false, "Log debug information.")
 
 	exploreCmd.PersistentFlags().StringVar(&query, "query", "", "Query which should be executed.")
@@ -1,10 +1,12 @@
 module github.com/ricoberger/dash
 
-go 1.13
+go 1.16
 
 require (
+	github.com/go-sql-driver/mysql v1.5.0
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/kylelemons/godebug v1.1.0 // indirect
+	github.com/lib/pq v1.9.0
 	github.com/mum4k/termdash v0.11.0
 	github.com/nsf/termbox-go v0.0.0-20190121233118-02980233997d // indirect
 	github.com/olekukonko/tablewriter v0.0.4
@@ -14,4 +16,5 @@ require (
 	github.com/stretchr/testify v1.4.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20190902080502-41f04d3bba15 // indirect
 	gopkg.in/yaml.v2 v2.2.7
+	modernc.org/sqlite v1.17.3
 )
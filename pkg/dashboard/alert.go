@@ -0,0 +1,57 @@
+package dashboard
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Alert is a rule, which is evaluated for each series of a graph on every refresh. The alert is active, when the stat
+// of a series matches the condition, e.g. "max > 90". An active alert starts firing, when it was active for the
+// duration defined in For.
+type Alert struct {
+	Name     string  `yaml:"name"`
+	Stat     string  `yaml:"stat"`
+	Operator string  `yaml:"operator"`
+	Value    float64 `yaml:"value"`
+	For      string  `yaml:"for"`
+	Severity string  `yaml:"severity"`
+}
+
+// GetStat returns the stat which is used to evaluate the alert. If no stat is set the current value is used.
+func (a *Alert) GetStat() string {
+	if a.Stat == "" {
+		return "current"
+	}
+
+	return a.Stat
+}
+
+// GetFor returns the duration an alert must be active before it starts firing.
+func (a *Alert) GetFor() (time.Duration, error) {
+	return parseOffset(a.For)
+}
+
+// Check returns true, when the given value matches the condition of the alert. NaN values never match the condition.
+func (a *Alert) Check(value float64) (bool, error) {
+	if math.IsNaN(value) {
+		return false, nil
+	}
+
+	switch a.Operator {
+	case ">":
+		return value > a.Value, nil
+	case ">=":
+		return value >= a.Value, nil
+	case "<":
+		return value < a.Value, nil
+	case "<=":
+		return value <= a.Value, nil
+	case "==":
+		return value == a.Value, nil
+	case "!=":
+		return value != a.Value, nil
+	default:
+		return false, fmt.Errorf("invalid operator %s for alert %s", a.Operator, a.Name)
+	}
+}
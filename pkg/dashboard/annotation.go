@@ -0,0 +1,102 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/datasource"
+)
+
+// Annotation is a query, which marks events like deployments or alerts on all linecharts of a dashboard. An event is
+// created each time a series of the query changes from no value or zero to a value other than zero, e.g. for the query
+// "changes(kube_deployment_status_observed_generation[1m]) > 0" or "ALERTS{alertstate="firing"}".
+type Annotation struct {
+	Name       string `yaml:"name"`
+	Datasource string `yaml:"datasource"`
+	Query      string `yaml:"query"`
+	Label      string `yaml:"label"`
+	Color      string `yaml:"color"`
+}
+
+// Event is a single occurrence of an annotation.
+type Event struct {
+	Name  string
+	Label string
+	Color string
+	Time  time.Time
+}
+
+// GetAnnotations returns the events of all annotations of the dashboard sorted by their time. Annotations without a
+// datasource are using the given datasource.
+func (d *Dashboard) GetAnnotations(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string, start, end time.Time) ([]Event, error) {
+	var events []Event
+
+	for _, annotation := range d.Annotations {
+		client := ds
+		if annotation.Datasource != "" {
+			var ok bool
+			client, ok = datasources[annotation.Datasource]
+			if !ok {
+				return nil, fmt.Errorf("datasource %s not found", annotation.Datasource)
+			}
+		}
+
+		e, err := annotation.GetEvents(ctx, client, variables, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e...)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events, nil
+}
+
+func (a *Annotation) GetEvents(ctx context.Context, ds datasource.Client, variables map[string]string, start, end time.Time) ([]Event, error) {
+	query, err := datasource.QueryInterpolation(a.Query, withTimeRange(variables, start, end))
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ds.GetData(ctx, []string{query}, []string{a.Label}, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+
+	for _, series := range data.Series {
+		active := false
+
+		for index, point := range series.Points {
+			if index >= len(data.Times) {
+				break
+			}
+
+			if math.IsNaN(point) || point == 0 {
+				active = false
+				continue
+			}
+
+			if !active {
+				events = append(events, Event{
+					Name:  a.Name,
+					Label: series.Label,
+					Color: a.Color,
+					Time:  data.Times[index],
+				})
+			}
+
+			active = true
+		}
+	}
+
+	return events, nil
+}
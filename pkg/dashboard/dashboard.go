@@ -3,6 +3,8 @@ package dashboard
 import (
 	"io/ioutil"
 	"path/filepath"
+	"strconv"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -13,10 +15,12 @@ type Row struct {
 }
 
 type Dashboard struct {
-	Name              string     `yaml:"name"`
-	DefaultDatasource string     `yaml:"defaultDatasource"`
-	Variables         []Variable `yaml:"variables"`
-	Rows              []Row      `yaml:"rows"`
+	Name              string       `yaml:"name"`
+	DefaultDatasource string       `yaml:"defaultDatasource"`
+	Timezone          string       `yaml:"timezone"`
+	Variables         []Variable   `yaml:"variables"`
+	Annotations       []Annotation `yaml:"annotations"`
+	Rows              []Row        `yaml:"rows"`
 }
 
 func New(dir string) ([]Dashboard, error) {
@@ -77,3 +81,18 @@ func Explore(query string) ([]Dashboard, error) {
 
 	return []Dashboard{dashboard}, nil
 }
+
+// withTimeRange returns a copy of the given variables, which also contains the start and end time as unix timestamps,
+// so that they can be used within a query via "{{.start}}" and "{{.end}}". Variables of the dashboard with the same
+// name are not overwritten.
+func withTimeRange(variables map[string]string, start, end time.Time) map[string]string {
+	result := make(map[string]string)
+	result["start"] = strconv.FormatInt(start.Unix(), 10)
+	result["end"] = strconv.FormatInt(end.Unix(), 10)
+
+	for key, value := range variables {
+		result[key] = value
+	}
+
+	return result
+}
@@ -1,11 +1,29 @@
 package dashboard
 
 import (
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ricoberger/dash/pkg/datasource"
 )
 
+var (
+	offsetRegexp = regexp.MustCompile(`(\d+)(ms|s|m|h|d|w|y)`)
+	offsetUnits  = map[string]time.Duration{
+		"ms": time.Millisecond,
+		"s":  time.Second,
+		"m":  time.Minute,
+		"h":  time.Hour,
+		"d":  24 * time.Hour,
+		"w":  7 * 24 * time.Hour,
+		"y":  365 * 24 * time.Hour,
+	}
+)
+
 type Graph struct {
 	Width      int     `yaml:"width"`
 	Datasource string  `yaml:"datasource"`
@@ -13,11 +31,39 @@ type Graph struct {
 	Title      string  `yaml:"title"`
 	Queries    []Query `yaml:"queries"`
 	Options    Options `yaml:"options"`
+	Alerts     []Alert `yaml:"alerts"`
 }
 
 type Query struct {
-	Query string `yaml:"query"`
-	Label string `yaml:"label"`
+	Datasource string `yaml:"datasource"`
+	Query      string `yaml:"query"`
+	Label      string `yaml:"label"`
+	Offset     string `yaml:"offset"`
+}
+
+// queryGroup contains all queries of a graph, which are using the same datasource and offset, so that they can be sent
+// to the datasource with one request.
+type queryGroup struct {
+	datasource string
+	offset     string
+	duration   time.Duration
+	queries    []string
+	labels     []string
+}
+
+// client returns the client for the datasource of the query group. If the group has no datasource the given datasource
+// is used.
+func (q *queryGroup) client(ds datasource.Client, datasources map[string]datasource.Client) (datasource.Client, error) {
+	if q.datasource == "" {
+		return ds, nil
+	}
+
+	client, ok := datasources[q.datasource]
+	if !ok {
+		return nil, fmt.Errorf("datasource %s not found", q.datasource)
+	}
+
+	return client, nil
 }
 
 type Options struct {
@@ -29,6 +75,11 @@ type Options struct {
 	Legend     string            `yaml:"legend"`
 	Mappings   map[string]string `yaml:"mappings"`
 	Columns    []Column          `yaml:"columns"`
+	Limit      int               `yaml:"limit"`
+	Filter     string            `yaml:"filter"`
+	TopN       int               `yaml:"topN"`
+	TopNStat   string            `yaml:"topNStat"`
+	Stacked    bool              `yaml:"stacked"`
 }
 
 type Column struct {
@@ -36,7 +87,131 @@ type Column struct {
 	Header string `yaml:"header"`
 }
 
-func (g *Graph) GetData(ds datasource.Client, variables map[string]string, start, end time.Time) (*datasource.Data, error) {
+// GetData returns the data for all queries of the graph. Queries without a datasource are using the given datasource,
+// all other queries are using the configured datasource. The queries are grouped by their datasource and offset, so
+// that each datasource is only called once per offset. If the graph uses multiple datasources or offsets the results
+// are merged, so that all series are using the same timestamps.
+//
+// Queries with an offset are executed for the time range shifted by the offset into the past. The returned points are
+// moved back to the current time range and the offset is added to the label of the series, so that the series can be
+// compared with the series of the current time range.
+func (g *Graph) GetData(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string, start, end time.Time) (*datasource.Data, error) {
+	groups, err := g.queryGroups(variables, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []*datasource.Data
+
+	// If the caller traces the request, the step is only recorded when the points of all groups are aligned to the same
+	// step, so that the caller can rely on the step for all series of the graph.
+	outer, tracing := datasource.TraceFromContext(ctx)
+	var step time.Duration
+
+	for index, group := range groups {
+		client, err := group.client(ds, datasources)
+		if err != nil {
+			return nil, err
+		}
+
+		var trace datasource.Trace
+		data, err := client.GetData(datasource.WithTrace(ctx, &trace), group.queries, group.labels, start.Add(-group.duration), end.Add(-group.duration))
+		if err != nil {
+			return nil, err
+		}
+
+		if !trace.Aligned || (index > 0 && trace.Step != step) {
+			step = 0
+		} else if index == 0 {
+			step = trace.Step
+		}
+
+		if group.duration != 0 {
+			data = shiftData(data, group.duration, group.offset)
+		}
+
+		results = append(results, data)
+	}
+
+	if tracing {
+		outer.Start = start
+		outer.End = end
+		outer.Step = step
+		outer.Aligned = step > 0
+	}
+
+	if len(results) == 1 {
+		return results[0], nil
+	}
+
+	return datasource.MergeData(results...), nil
+}
+
+// queryGroups returns the interpolated queries of the graph grouped by their datasource and offset. The queries of a
+// group with an offset are interpolated with the shifted time range.
+func (g *Graph) queryGroups(variables map[string]string, start, end time.Time) ([]*queryGroup, error) {
+	var groups []*queryGroup
+
+	for _, query := range g.Queries {
+		offset, err := parseOffset(query.Offset)
+		if err != nil {
+			return nil, err
+		}
+
+		q, err := datasource.QueryInterpolation(query.Query, withTimeRange(variables, start.Add(-offset), end.Add(-offset)))
+		if err != nil {
+			return nil, err
+		}
+
+		var group *queryGroup
+		for _, g := range groups {
+			if g.datasource == query.Datasource && g.offset == query.Offset {
+				group = g
+				break
+			}
+		}
+
+		if group == nil {
+			group = &queryGroup{datasource: query.Datasource, offset: query.Offset, duration: offset}
+			groups = append(groups, group)
+		}
+
+		group.queries = append(group.queries, q)
+		group.labels = append(group.labels, query.Label)
+	}
+
+	return groups, nil
+}
+
+// queriesClient returns the client and the name of the datasource for graph types, which are sending all queries with
+// one request to a datasource. All queries of these graphs must use the same datasource, because the results can not be
+// merged. If the queries have no datasource the given datasource is used.
+func (g *Graph) queriesClient(ds datasource.Client, datasources map[string]datasource.Client) (datasource.Client, string, error) {
+	var name string
+	for index, query := range g.Queries {
+		if index > 0 && query.Datasource != name {
+			return nil, "", fmt.Errorf("all queries of a %s graph must use the same datasource", g.Type)
+		}
+		name = query.Datasource
+	}
+
+	group := queryGroup{datasource: name}
+	client, err := group.client(ds, datasources)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return client, name, nil
+}
+
+// GetTableData returns the data for a table. The queries are using the datasource of the queries or the given
+// datasource, when the queries have no datasource.
+func (g *Graph) GetTableData(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string) (*datasource.TableData, error) {
+	client, _, err := g.queriesClient(ds, datasources)
+	if err != nil {
+		return nil, err
+	}
+
 	var queries []string
 	var labels []string
 
@@ -50,13 +225,25 @@ func (g *Graph) GetData(ds datasource.Client, variables map[string]string, start
 		labels = append(labels, query.Label)
 	}
 
-	return ds.GetData(queries, labels, start, end)
+	return client.GetTableData(ctx, queries, labels)
 }
 
-func (g *Graph) GetTableData(ds datasource.Client, variables map[string]string) (*datasource.TableData, error) {
+func (g *Graph) GetLogs(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string, start, end time.Time) ([]datasource.LogLine, error) {
+	client, _, err := g.queriesClient(ds, datasources)
+	if err != nil {
+		return nil, err
+	}
+
+	logsClient, ok := client.(datasource.LogsClient)
+	if !ok {
+		return nil, datasource.ErrLogsNotSupported
+	}
+
 	var queries []string
 	var labels []string
 
+	variables = withTimeRange(variables, start, end)
+
 	for _, query := range g.Queries {
 		q, err := datasource.QueryInterpolation(query.Query, variables)
 		if err != nil {
@@ -67,5 +254,81 @@ func (g *Graph) GetTableData(ds datasource.Client, variables map[string]string)
 		labels = append(labels, query.Label)
 	}
 
-	return ds.GetTableData(queries, labels)
+	limit := g.Options.Limit
+	if limit == 0 {
+		limit = 100
+	}
+
+	return logsClient.GetLogs(ctx, queries, labels, start, end, limit)
+}
+
+func (g *Graph) GetAlerts(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string) ([]datasource.Alert, error) {
+	client, _, err := g.queriesClient(ds, datasources)
+	if err != nil {
+		return nil, err
+	}
+
+	alertsClient, ok := client.(datasource.AlertsClient)
+	if !ok {
+		return nil, datasource.ErrAlertsNotSupported
+	}
+
+	var queries []string
+
+	for _, query := range g.Queries {
+		q, err := datasource.QueryInterpolation(query.Query, variables)
+		if err != nil {
+			return nil, err
+		}
+
+		queries = append(queries, q)
+	}
+
+	return alertsClient.GetAlerts(ctx, queries)
+}
+
+// shiftData returns a copy of the given data, where all timestamps are moved by the given offset into the future. The
+// data is copied, because the returned data of a datasource can be shared with other graphs.
+func shiftData(data *datasource.Data, offset time.Duration, label string) *datasource.Data {
+	shifted := &datasource.Data{}
+
+	for _, t := range data.Times {
+		shifted.Times = append(shifted.Times, t.Add(offset))
+	}
+
+	for _, series := range data.Series {
+		shifted.Series = append(shifted.Series, datasource.Series{
+			Label:     fmt.Sprintf("%s (offset %s)", series.Label, label),
+			Labels:    series.Labels,
+			Points:    series.Points,
+			TimeShift: offset,
+		})
+	}
+
+	return shifted
+}
+
+// parseOffset parses the offset of a query. The offset is a duration like "1h", "1d" or "1w12h". Besides the units
+// supported by Go, the units d (day), w (week) and y (year) can be used.
+func parseOffset(offset string) (time.Duration, error) {
+	if offset == "" {
+		return 0, nil
+	}
+
+	matches := offsetRegexp.FindAllStringSubmatch(offset, -1)
+	if matches == nil || strings.Join(offsetRegexp.FindAllString(offset, -1), "") != offset {
+		return 0, fmt.Errorf("invalid offset %s", offset)
+	}
+
+	var duration time.Duration
+	for _, match := range matches {
+		count, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid offset %s: %s", offset, err.Error())
+		}
+
+		duration = duration + time.Duration(count)*offsetUnits[match[2]]
+	}
+
+	return duration, nil
 }
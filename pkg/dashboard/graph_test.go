@@ -0,0 +1,81 @@
+package dashboard
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/datasource"
+)
+
+func TestGraphQueriesClient(t *testing.T) {
+	ds := &datasource.File{}
+	other := &datasource.File{}
+	datasources := map[string]datasource.Client{"other": other}
+
+	graph := Graph{Type: "table", Queries: []Query{{Query: "a"}, {Query: "b"}}}
+	if client, _, err := graph.queriesClient(ds, datasources); err != nil || client != ds {
+		t.Errorf("expected the default datasource, got %v, %v", client, err)
+	}
+
+	graph.Queries = []Query{{Datasource: "other", Query: "a"}, {Datasource: "other", Query: "b"}}
+	if client, name, err := graph.queriesClient(ds, datasources); err != nil || client != other || name != "other" {
+		t.Errorf("expected the datasource of the queries, got %v, %s, %v", client, name, err)
+	}
+
+	graph.Queries = []Query{{Query: "a"}, {Datasource: "other", Query: "b"}}
+	if _, _, err := graph.queriesClient(ds, datasources); err == nil {
+		t.Error("expected an error for queries with different datasources")
+	}
+
+	graph.Queries = []Query{{Datasource: "unknown", Query: "a"}}
+	if _, _, err := graph.queriesClient(ds, datasources); err == nil {
+		t.Error("expected an error for an unknown datasource")
+	}
+}
+
+// stepClient is a datasource, which records the given step in the trace of a request.
+type stepClient struct {
+	datasource.File
+	step    time.Duration
+	aligned bool
+}
+
+func (c *stepClient) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*datasource.Data, error) {
+	if trace, ok := datasource.TraceFromContext(ctx); ok {
+		trace.Step = c.step
+		trace.Aligned = c.aligned
+	}
+
+	return &datasource.Data{Times: []time.Time{start}}, nil
+}
+
+func TestGraphGetDataTrace(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := start.Add(time.Hour)
+
+	for _, tc := range []struct {
+		name        string
+		datasources map[string]datasource.Client
+		expected    time.Duration
+	}{
+		{name: "same step", datasources: map[string]datasource.Client{"other": &stepClient{step: time.Minute, aligned: true}}, expected: time.Minute},
+		{name: "different step", datasources: map[string]datasource.Client{"other": &stepClient{step: time.Second, aligned: true}}, expected: 0},
+		{name: "not aligned", datasources: map[string]datasource.Client{"other": &stepClient{step: time.Minute}}, expected: 0},
+		{name: "no step", datasources: map[string]datasource.Client{"other": &stepClient{}}, expected: 0},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			graph := Graph{Queries: []Query{{Query: "a"}, {Datasource: "other", Query: "b"}}}
+
+			var trace datasource.Trace
+			_, err := graph.GetData(datasource.WithTrace(context.Background(), &trace), &stepClient{step: time.Minute, aligned: true}, tc.datasources, nil, start, end)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if trace.Step != tc.expected {
+				t.Errorf("expected step %s, got %s", tc.expected, trace.Step)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package dashboard
+
+import (
+	"context"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/datasource"
+)
+
+// Inspection contains the details of a single request against a datasource, which was sent to load a graph. For graph
+// types which are not showing time series, Series is empty and Results contains the number of returned rows, log lines
+// or alerts.
+type Inspection struct {
+	Datasource string
+	Offset     string
+	Queries    []string
+	Labels     []string
+	Trace      datasource.Trace
+	Duration   time.Duration
+	Series     []datasource.Series
+	Results    int
+	Err        error
+}
+
+// Inspect loads the data for the graph and returns the details of each request against a datasource. The requests are
+// not using the cache of the datasources, so that the returned durations are the real durations of the requests.
+// Errors of a request are returned in the inspection of the request, so that the other requests can still be
+// inspected.
+func (g *Graph) Inspect(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string, start, end time.Time) ([]Inspection, error) {
+	switch g.Type {
+	case "table", "logs", "alertlist":
+		return g.inspectQueries(ctx, ds, datasources, variables, start, end)
+	}
+
+	groups, err := g.queryGroups(variables, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var inspections []Inspection
+
+	for _, group := range groups {
+		inspection := Inspection{
+			Datasource: group.datasource,
+			Offset:     group.offset,
+			Queries:    group.queries,
+			Labels:     group.labels,
+		}
+
+		client, err := group.client(ds, datasources)
+		if err != nil {
+			inspection.Err = err
+			inspections = append(inspections, inspection)
+			continue
+		}
+
+		started := time.Now()
+		data, err := uncached(client).GetData(datasource.WithTrace(ctx, &inspection.Trace), group.queries, group.labels, start.Add(-group.duration), end.Add(-group.duration))
+		inspection.Duration = time.Since(started)
+		inspection.Err = err
+
+		if data != nil {
+			inspection.Series = data.Series
+			inspection.Results = len(data.Series)
+		}
+
+		inspections = append(inspections, inspection)
+	}
+
+	return inspections, nil
+}
+
+// inspectQueries inspects the request for graph types, which are sending all queries with one request to a datasource.
+func (g *Graph) inspectQueries(ctx context.Context, ds datasource.Client, datasources map[string]datasource.Client, variables map[string]string, start, end time.Time) ([]Inspection, error) {
+	inspection := Inspection{
+		Trace: datasource.Trace{Start: start, End: end},
+	}
+
+	_, name, err := g.queriesClient(ds, datasources)
+	inspection.Datasource = name
+	if err != nil {
+		inspection.Err = err
+		return []Inspection{inspection}, nil
+	}
+
+	if g.Type == "logs" {
+		variables = withTimeRange(variables, start, end)
+	}
+
+	uncachedDatasources := make(map[string]datasource.Client)
+	for name, client := range datasources {
+		uncachedDatasources[name] = uncached(client)
+	}
+
+	for _, query := range g.Queries {
+		q, err := datasource.QueryInterpolation(query.Query, variables)
+		if err != nil {
+			return nil, err
+		}
+
+		inspection.Queries = append(inspection.Queries, q)
+		inspection.Labels = append(inspection.Labels, query.Label)
+	}
+
+	started := time.Now()
+
+	switch g.Type {
+	case "table":
+		data, err := g.GetTableData(ctx, uncached(ds), uncachedDatasources, variables)
+		inspection.Err = err
+		if data != nil {
+			inspection.Results = len(*data)
+		}
+	case "logs":
+		lines, err := g.GetLogs(ctx, uncached(ds), uncachedDatasources, variables, start, end)
+		inspection.Err = err
+		inspection.Results = len(lines)
+	case "alertlist":
+		alerts, err := g.GetAlerts(ctx, uncached(ds), uncachedDatasources, variables)
+		inspection.Err = err
+		inspection.Results = len(alerts)
+	}
+
+	inspection.Duration = time.Since(started)
+
+	return []Inspection{inspection}, nil
+}
+
+// uncached returns the client wrapped by a cache or the client itself, when it is not wrapped by a cache.
+func uncached(client datasource.Client) datasource.Client {
+	if cache, ok := client.(*datasource.Cache); ok {
+		return cache.Client()
+	}
+
+	return client
+}
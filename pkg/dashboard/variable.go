@@ -1,6 +1,7 @@
 package dashboard
 
 import (
+	"context"
 	"time"
 
 	"github.com/ricoberger/dash/pkg/datasource"
@@ -13,13 +14,13 @@ type Variable struct {
 	All   bool   `yaml:"all"`
 }
 
-func (v *Variable) GetValues(ds datasource.Client, variables map[string]string, start, end time.Time) ([]string, error) {
-	query, err := datasource.QueryInterpolation(v.Query, variables)
+func (v *Variable) GetValues(ctx context.Context, ds datasource.Client, variables map[string]string, start, end time.Time) ([]string, error) {
+	query, err := datasource.QueryInterpolation(v.Query, withTimeRange(variables, start, end))
 	if err != nil {
 		return nil, err
 	}
 
-	values, err := ds.GetVariableValues(query, v.Label, start, end)
+	values, err := ds.GetVariableValues(ctx, query, v.Label, start, end)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,277 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+// Alertmanager implements the Client and AlertsClient interface for the Alertmanager v2 API. A query is a matcher for
+// the labels of the alerts in the format "{severity="critical",namespace=~"{{.namespace}}"}". Only active alerts,
+// which are not silenced or inhibited are returned.
+//
+// Graphs are showing the number of matching alerts, tables are showing one row per alert and variables are returning
+// the values of the label from all matching alerts.
+type Alertmanager struct {
+	client  *http.Client
+	url     string
+	options Options
+}
+
+type alertmanagerAlert struct {
+	Fingerprint string            `json:"fingerprint"`
+	Labels      map[string]string `json:"labels"`
+	Annotations map[string]string `json:"annotations"`
+	StartsAt    time.Time         `json:"startsAt"`
+	Status      struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+func NewAlertmanagerClient(datasource Datasource) (*Alertmanager, error) {
+	_, err := url.Parse(datasource.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Alertmanager{
+		client:  &http.Client{Transport: newRoundTripper(datasource.Auth)},
+		url:     strings.TrimSuffix(datasource.URL, "/"),
+		options: datasource.Options,
+	}, nil
+}
+
+func (a *Alertmanager) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(a.options))
+	defer cancel()
+
+	alerts, err := a.getAlerts(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, alert := range alerts {
+		if value, ok := alert.Labels[label]; ok {
+			values = appendIfMissing(values, value)
+		}
+	}
+
+	sort.Strings(values)
+	return values, nil
+}
+
+func (a *Alertmanager) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(a.options))
+	defer cancel()
+
+	var series []Series
+
+	for i, query := range queries {
+		alerts, err := a.getAlerts(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		label := labels[i]
+		if label == "" {
+			label = query
+		}
+
+		series = append(series, Series{
+			Label:  label,
+			Points: []float64{float64(len(alerts))},
+		})
+	}
+
+	return &Data{
+		Times:  []time.Time{end},
+		Series: series,
+	}, nil
+}
+
+func (a *Alertmanager) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(a.options))
+	defer cancel()
+
+	tableData := make(TableData)
+
+	for _, query := range queries {
+		alerts, err := a.getAlerts(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, alert := range alerts {
+			row := make(map[string]interface{})
+			for key, value := range alert.Labels {
+				row[key] = value
+			}
+			row["state"] = alert.Status.State
+			row["startsAt"] = alert.StartsAt.Format("01/02 15:04:05")
+
+			tableData[alert.Fingerprint] = row
+		}
+	}
+
+	return &tableData, nil
+}
+
+func (a *Alertmanager) GetSuggestions(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(a.options))
+	defer cancel()
+
+	alerts, err := a.getAlerts(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var suggestions []string
+	for _, alert := range alerts {
+		suggestions = appendIfMissing(suggestions, fmt.Sprintf("{alertname=%q}", alert.Labels["alertname"]))
+	}
+
+	sort.Strings(suggestions)
+	return suggestions, nil
+}
+
+// GetAlerts returns all active alerts, which are matching one of the given queries. The alerts are sorted by the time
+// they started firing, beginning with the newest alert.
+func (a *Alertmanager) GetAlerts(ctx context.Context, queries []string) ([]Alert, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(a.options))
+	defer cancel()
+
+	var alerts []Alert
+	fingerprints := make(map[string]bool)
+
+	for _, query := range queries {
+		results, err := a.getAlerts(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, result := range results {
+			if fingerprints[result.Fingerprint] {
+				continue
+			}
+			fingerprints[result.Fingerprint] = true
+
+			alerts = append(alerts, Alert{
+				Name:        result.Labels["alertname"],
+				Severity:    result.Labels["severity"],
+				State:       result.Status.State,
+				Labels:      result.Labels,
+				Annotations: result.Annotations,
+				StartsAt:    result.StartsAt,
+			})
+		}
+	}
+
+	sort.SliceStable(alerts, func(i, j int) bool {
+		return alerts[i].StartsAt.After(alerts[j].StartsAt)
+	})
+
+	return alerts, nil
+}
+
+func (a *Alertmanager) getAlerts(ctx context.Context, query string) ([]alertmanagerAlert, error) {
+	matchers, err := parseAlertmanagerMatchers(query)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("active", "true")
+	params.Set("silenced", "false")
+	params.Set("inhibited", "false")
+	for _, matcher := range matchers {
+		params.Add("filter", matcher)
+	}
+
+	fLog.Debugf("get alerts for %v", matchers)
+
+	req, err := http.NewRequest(http.MethodGet, a.url+"/api/v2/alerts?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := a.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var alerts []alertmanagerAlert
+	err = json.Unmarshal(body, &alerts)
+	if err != nil {
+		return nil, err
+	}
+
+	return alerts, nil
+}
+
+// parseAlertmanagerMatchers splits a query like "{severity="critical",namespace=~"default|kube-system"}" into the
+// single matchers, which are passed as filter to the Alertmanager API. Commas within quoted values are not used as
+// separator.
+func parseAlertmanagerMatchers(query string) ([]string, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || query == "{}" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(query, "{") || !strings.HasSuffix(query, "}") {
+		return nil, fmt.Errorf("invalid query %s: matchers must be enclosed in curly braces", query)
+	}
+
+	var matchers []string
+	var current strings.Builder
+	quoted := false
+	escaped := false
+
+	for _, r := range query[1 : len(query)-1] {
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case r == ',' && !quoted:
+			if matcher := strings.TrimSpace(current.String()); matcher != "" {
+				matchers = append(matchers, matcher)
+			}
+			current.Reset()
+			continue
+		}
+
+		current.WriteRune(r)
+	}
+
+	if quoted {
+		return nil, fmt.Errorf("invalid query %s: unterminated quote", query)
+	}
+
+	if matcher := strings.TrimSpace(current.String()); matcher != "" {
+		matchers = append(matchers, matcher)
+	}
+
+	return matchers, nil
+}
@@ -0,0 +1,119 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseAlertmanagerMatchers(t *testing.T) {
+	for _, tc := range []struct {
+		query    string
+		expected []string
+		err      bool
+	}{
+		{query: "", expected: nil},
+		{query: "{}", expected: nil},
+		{query: `{severity="critical", namespace=~"default|kube-system"}`, expected: []string{`severity="critical"`, `namespace=~"default|kube-system"`}},
+		{query: `{summary="a, b",instance!="x\"y"}`, expected: []string{`summary="a, b"`, `instance!="x\"y"`}},
+		{query: `severity="critical"`, err: true},
+		{query: `{severity="critical}`, err: true},
+	} {
+		matchers, err := parseAlertmanagerMatchers(tc.query)
+		if tc.err {
+			if err == nil {
+				t.Errorf("expected an error for %s", tc.query)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("unexpected error for %s: %s", tc.query, err.Error())
+		} else if !reflect.DeepEqual(matchers, tc.expected) {
+			t.Errorf("unexpected matchers for %s: %q", tc.query, matchers)
+		}
+	}
+}
+
+func TestAlertmanagerGetAlerts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		query := r.URL.Query()
+		if query.Get("active") != "true" || query.Get("silenced") != "false" || query.Get("inhibited") != "false" {
+			t.Errorf("unexpected parameters %s", r.URL.RawQuery)
+		}
+
+		filters := query["filter"]
+		switch {
+		case reflect.DeepEqual(filters, []string{`severity="critical"`, `namespace=~"default|kube-system"`}):
+			fmt.Fprint(w, `[
+				{"fingerprint":"a","labels":{"alertname":"KubePodCrashLooping","severity":"critical","namespace":"default"},"annotations":{"summary":"Pod is crash looping"},"startsAt":"2020-01-01T00:00:00.000Z","status":{"state":"active"}},
+				{"fingerprint":"b","labels":{"alertname":"KubeNodeNotReady","severity":"critical","namespace":"kube-system"},"startsAt":"2020-01-01T00:30:00.123+01:00","status":{"state":"active"}}
+			]`)
+		case reflect.DeepEqual(filters, []string{`alertname="KubePodCrashLooping"`}):
+			fmt.Fprint(w, `[{"fingerprint":"a","labels":{"alertname":"KubePodCrashLooping","severity":"critical","namespace":"default"},"startsAt":"2020-01-01T00:00:00.000Z","status":{"state":"active"}}]`)
+		default:
+			t.Errorf("unexpected filters %q", filters)
+			w.WriteHeader(http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewAlertmanagerClient(Datasource{URL: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query, err := QueryInterpolation(`{severity="critical",namespace=~"{{.namespace}}"}`, map[string]string{"namespace": "default|kube-system"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alerts, err := client.GetAlerts(context.Background(), []string{query, `{alertname="KubePodCrashLooping"}`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The alert of the second query is already returned for the first query and the alerts are sorted by their start
+	// time, beginning with the newest alert.
+	if len(alerts) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(alerts))
+	}
+
+	if alerts[0].Name != "KubePodCrashLooping" || alerts[0].Severity != "critical" || alerts[0].State != "active" || alerts[0].Labels["namespace"] != "default" || alerts[0].Annotations["summary"] != "Pod is crash looping" {
+		t.Errorf("unexpected alert %v", alerts[0])
+	}
+
+	if !alerts[0].StartsAt.Equal(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start time %s", alerts[0].StartsAt)
+	}
+
+	if alerts[1].Name != "KubeNodeNotReady" || !alerts[1].StartsAt.Equal(time.Date(2019, 12, 31, 23, 30, 0, 123000000, time.UTC)) {
+		t.Errorf("unexpected alert %v", alerts[1])
+	}
+}
+
+func TestAlertmanagerError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "bad matcher")
+	}))
+	defer server.Close()
+
+	client, err := NewAlertmanagerClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetAlerts(context.Background(), []string{`{severity="critical"}`})
+	if err == nil || err.Error() != "unexpected status code 400: bad matcher" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+// Cache wraps a Client and caches the results of all queries. The results are cached by the query, labels, time range
+// and step, where the time range is aligned to seconds. Identical requests which are running at the same time are only
+// sent once to the datasource and all callers are getting the same result. Errors are not cached.
+//
+// The returned results are shared between all callers, so that they must not be modified.
+type Cache struct {
+	client  Client
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done     chan struct{}
+	finished bool
+	canceled bool
+	expires  time.Time
+	value    interface{}
+	err      error
+}
+
+// cachedData is the cached result of a GetData call.
+type cachedData struct {
+	data  *Data
+	trace Trace
+}
+
+func NewCache(client Client, ttl time.Duration) *Cache {
+	return &Cache{
+		client:  client,
+		ttl:     ttl,
+		entries: make(map[string]*cacheEntry),
+	}
+}
+
+// Client returns the wrapped client, so that queries can be sent to the datasource without using the cache.
+func (c *Cache) Client() Client {
+	return c.client
+}
+
+// SetTTL changes the time how long results are cached. The new ttl is only used for new results.
+func (c *Cache) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+}
+
+// Flush removes all cached results. Requests which are still running are not affected.
+func (c *Cache) Flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*cacheEntry)
+}
+
+func (c *Cache) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	key := fmt.Sprintf("variable|%q|%q|%d|%d", query, label, start.Unix(), end.Unix())
+
+	value, err := c.do(ctx, key, func() (interface{}, error) {
+		return c.client.GetVariableValues(ctx, query, label, start, end)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+func (c *Cache) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	// The step of the context is part of the key, because the step overrides the step of the datasource, so that the
+	// same query and time range can return different points.
+	step, _ := ctx.Value(stepKey{}).(time.Duration)
+	key := fmt.Sprintf("data|%q|%q|%d|%d|%d", queries, labels, start.Unix(), end.Unix(), step)
+
+	// The trace is cached with the data, so that callers which are getting a cached result are also getting the time
+	// range and step of the query.
+	value, err := c.do(ctx, key, func() (interface{}, error) {
+		var trace Trace
+		data, err := c.client.GetData(WithTrace(ctx, &trace), queries, labels, start, end)
+		return cachedData{data: data, trace: trace}, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	cached := value.(cachedData)
+	if trace, ok := TraceFromContext(ctx); ok {
+		*trace = cached.trace
+	}
+
+	return cached.data, nil
+}
+
+func (c *Cache) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	key := fmt.Sprintf("table|%q|%q", queries, labels)
+
+	value, err := c.do(ctx, key, func() (interface{}, error) {
+		return c.client.GetTableData(ctx, queries, labels)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.(*TableData), nil
+}
+
+func (c *Cache) GetSuggestions(ctx context.Context) ([]string, error) {
+	value, err := c.do(ctx, "suggestions", func() (interface{}, error) {
+		return c.client.GetSuggestions(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]string), nil
+}
+
+// GetLogs returns the log lines from the wrapped client. If the wrapped client doesn't support logs ErrLogsNotSupported
+// is returned.
+func (c *Cache) GetLogs(ctx context.Context, queries, labels []string, start, end time.Time, limit int) ([]LogLine, error) {
+	logsClient, ok := c.client.(LogsClient)
+	if !ok {
+		return nil, ErrLogsNotSupported
+	}
+
+	key := fmt.Sprintf("logs|%q|%q|%d|%d|%d", queries, labels, start.Unix(), end.Unix(), limit)
+
+	value, err := c.do(ctx, key, func() (interface{}, error) {
+		return logsClient.GetLogs(ctx, queries, labels, start, end, limit)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]LogLine), nil
+}
+
+// GetAlerts returns the active alerts from the wrapped client. If the wrapped client doesn't support alerts
+// ErrAlertsNotSupported is returned.
+func (c *Cache) GetAlerts(ctx context.Context, queries []string) ([]Alert, error) {
+	alertsClient, ok := c.client.(AlertsClient)
+	if !ok {
+		return nil, ErrAlertsNotSupported
+	}
+
+	key := fmt.Sprintf("alerts|%q", queries)
+
+	value, err := c.do(ctx, key, func() (interface{}, error) {
+		return alertsClient.GetAlerts(ctx, queries)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return value.([]Alert), nil
+}
+
+// do returns the cached result for the given key. If there is no result or the result is expired, fn is called to get
+// the result. If there is already a running request for the key, do waits until the request is finished or the given
+// context is canceled. When the context of the caller which sent the request is canceled, the waiting callers are
+// sending the request again.
+func (c *Cache) do(ctx context.Context, key string, fn func() (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+
+	now := time.Now()
+	for k, e := range c.entries {
+		if e.finished && !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+
+	if e, ok := c.entries[key]; ok {
+		c.mu.Unlock()
+		fLog.Debugf("use cached result for %s", key)
+
+		select {
+		case <-e.done:
+			// If the request failed, because the context of the caller which sent the request was canceled, the
+			// request is sent again with the context of this caller.
+			if e.canceled && ctx.Err() == nil {
+				return c.do(ctx, key, fn)
+			}
+			return e.value, e.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	e := &cacheEntry{done: make(chan struct{})}
+	c.entries[key] = e
+	c.mu.Unlock()
+
+	value, err := fn()
+
+	c.mu.Lock()
+	e.value = value
+	e.err = err
+	e.finished = true
+	e.canceled = err != nil && ctx.Err() != nil
+	e.expires = time.Now().Add(c.ttl)
+	if err != nil && c.entries[key] == e {
+		delete(c.entries, key)
+	}
+	c.mu.Unlock()
+
+	close(e.done)
+	return value, err
+}
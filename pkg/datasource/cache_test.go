@@ -0,0 +1,187 @@
+package datasource
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingClient is a Client, which counts the requests for time series data.
+type countingClient struct {
+	mu       sync.Mutex
+	requests int
+}
+
+func (c *countingClient) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	return nil, nil
+}
+
+func (c *countingClient) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests++
+	return &Data{}, nil
+}
+
+func (c *countingClient) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	return nil, nil
+}
+
+func (c *countingClient) GetSuggestions(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func TestCacheGetData(t *testing.T) {
+	client := &countingClient{}
+	cache := NewCache(client, 50*time.Millisecond)
+
+	ctx := context.Background()
+	start := time.Unix(1577836800, 0)
+	end := start.Add(time.Hour)
+	queries := []string{"up"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.GetData(ctx, queries, nil, start, end); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if client.requests != 1 {
+		t.Fatalf("expected 1 request, got %d", client.requests)
+	}
+
+	// A different step must not return the result, which was cached for the step of the datasource.
+	if _, err := cache.GetData(WithStep(ctx, time.Minute), queries, nil, start, end); err != nil {
+		t.Fatal(err)
+	}
+	if client.requests != 2 {
+		t.Fatalf("expected 2 requests, got %d", client.requests)
+	}
+
+	// The cached results are removed when the ttl is expired.
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cache.GetData(ctx, queries, nil, start, end); err != nil {
+		t.Fatal(err)
+	}
+	if client.requests != 3 {
+		t.Fatalf("expected 3 requests, got %d", client.requests)
+	}
+
+	cache.Flush()
+	if _, err := cache.GetData(ctx, queries, nil, start, end); err != nil {
+		t.Fatal(err)
+	}
+	if client.requests != 4 {
+		t.Fatalf("expected 4 requests, got %d", client.requests)
+	}
+}
+
+// blockingClient is a Client, which blocks all requests for time series data until release is closed or the context
+// of the request is canceled.
+type blockingClient struct {
+	countingClient
+	started chan struct{}
+	release chan struct{}
+}
+
+func (c *blockingClient) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	c.mu.Lock()
+	c.requests++
+	c.mu.Unlock()
+
+	select {
+	case c.started <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-c.release:
+		return &Data{Times: []time.Time{end}}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func TestCacheDeduplicatesRequests(t *testing.T) {
+	client := &blockingClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	cache := NewCache(client, time.Minute)
+
+	start := time.Unix(1577836800, 0)
+	end := start.Add(time.Hour)
+
+	var wg sync.WaitGroup
+	results := make([]*Data, 5)
+	errs := make([]error, 5)
+
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = cache.GetData(context.Background(), []string{"up"}, nil, start, end)
+		}(i)
+	}
+
+	<-client.started
+	time.Sleep(20 * time.Millisecond)
+	close(client.release)
+	wg.Wait()
+
+	if client.requests != 1 {
+		t.Errorf("expected 1 request, got %d", client.requests)
+	}
+
+	for i := range results {
+		if errs[i] != nil || results[i] != results[0] {
+			t.Errorf("expected the shared result for caller %d, got %v, %v", i, results[i], errs[i])
+		}
+	}
+}
+
+func TestCacheCanceledRequest(t *testing.T) {
+	client := &blockingClient{started: make(chan struct{}, 1), release: make(chan struct{})}
+	cache := NewCache(client, time.Minute)
+
+	start := time.Unix(1577836800, 0)
+	end := start.Add(time.Hour)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error)
+	go func() {
+		_, err := cache.GetData(ctx, []string{"up"}, nil, start, end)
+		firstErr <- err
+	}()
+	<-client.started
+
+	secondErr := make(chan error)
+	var second *Data
+	go func() {
+		var err error
+		second, err = cache.GetData(context.Background(), []string{"up"}, nil, start, end)
+		secondErr <- err
+	}()
+
+	// The first caller is canceled while the second caller is waiting for the result of the first request. The second
+	// caller must not get the error of the first caller, but must send the request again.
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+
+	if err := <-firstErr; err != context.Canceled {
+		t.Errorf("expected the first caller to be canceled, got %v", err)
+	}
+
+	close(client.release)
+
+	if err := <-secondErr; err != nil || second == nil {
+		t.Fatalf("expected a result for the second caller, got %v, %v", second, err)
+	}
+
+	if client.requests != 2 {
+		t.Errorf("expected 2 requests, got %d", client.requests)
+	}
+
+	// The canceled request must not be cached, but the result of the second request.
+	if _, err := cache.GetData(context.Background(), []string{"up"}, nil, start, end); err != nil || client.requests != 2 {
+		t.Errorf("expected a cached result, got %v after %d requests", err, client.requests)
+	}
+}
@@ -2,18 +2,31 @@ package datasource
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"text/template"
 	"time"
 
 	"gopkg.in/yaml.v2"
 )
 
+var (
+	timeLayouts = []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02 15:04:05.999999999-07:00", "2006-01-02T15:04:05", "2006-01-02"}
+)
+
 var (
 	// ErrInvalidType is thrown when the provided datasource in a datasource file is invalid.
 	ErrInvalidType = errors.New("invalid datasource type")
+	// ErrLogsNotSupported is thrown when logs are requested from a datasource, which can not return log lines.
+	ErrLogsNotSupported = errors.New("datasource does not support logs")
+	// ErrAlertsNotSupported is thrown when alerts are requested from a datasource, which can not return alerts.
+	ErrAlertsNotSupported = errors.New("datasource does not support alerts")
 )
 
 type Auth struct {
@@ -26,33 +39,82 @@ type Auth struct {
 type Options struct {
 	MaxPoints int64 `yaml:"maxPoints"`
 	Step      int64 `yaml:"step"`
+	Timeout   int64 `yaml:"timeout"`
 }
 
 type Datasource struct {
-	Type    string  `yaml:"type"`
-	Name    string  `yaml:"name"`
-	URL     string  `yaml:"url"`
-	Auth    Auth    `yaml:"auth"`
-	Options Options `yaml:"options"`
+	Type         string  `yaml:"type"`
+	Name         string  `yaml:"name"`
+	URL          string  `yaml:"url"`
+	Driver       string  `yaml:"driver"`
+	Database     string  `yaml:"database"`
+	Organization string  `yaml:"organization"`
+	Language     string  `yaml:"language"`
+	TimeField    string  `yaml:"timeField"`
+	Format       string  `yaml:"format"`
+	Auth         Auth    `yaml:"auth"`
+	Options      Options `yaml:"options"`
 }
 
+// Data is the result of a query for a graph. Times contains the timestamp for each point of the series, so that the
+// results of multiple datasources can be merged.
 type Data struct {
-	Timestamps map[int]string
-	Series     []Series
+	Times  []time.Time
+	Series []Series
 }
 
+// Series is a single series of a graph. Labels contains the labels of the series as they were returned by the
+// datasource. If the series was queried for a shifted time range, TimeShift contains the duration by which the time
+// range was shifted.
 type Series struct {
-	Label  string
-	Points []float64
+	Label     string
+	Labels    map[string]string
+	Points    []float64
+	TimeShift time.Duration
 }
 
 type TableData map[string]map[string]interface{}
 
+type LogLine struct {
+	Timestamp time.Time
+	Label     string
+	Line      string
+}
+
+// Alert is an active alert, which is returned by datasources implementing the AlertsClient interface.
+type Alert struct {
+	Name        string
+	Severity    string
+	State       string
+	Labels      map[string]string
+	Annotations map[string]string
+	StartsAt    time.Time
+}
+
+// sample is a single point of a series. It is used by datasources, which are not returning the points of a series in
+// a fixed step, so that the points must be aligned before they can be rendered.
+type sample struct {
+	Label     string
+	Timestamp time.Time
+	Value     float64
+}
+
 type Client interface {
-	GetVariableValues(query, label string, start, end time.Time) ([]string, error)
-	GetData(queries, labels []string, start, end time.Time) (*Data, error)
-	GetTableData(queries, labels []string) (*TableData, error)
-	GetSuggestions() ([]string, error)
+	GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error)
+	GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error)
+	GetTableData(ctx context.Context, queries, labels []string) (*TableData, error)
+	GetSuggestions(ctx context.Context) ([]string, error)
+}
+
+// LogsClient is implemented by all datasources, which are able to return log lines in addition to the time series
+// data.
+type LogsClient interface {
+	GetLogs(ctx context.Context, queries, labels []string, start, end time.Time, limit int) ([]LogLine, error)
+}
+
+// AlertsClient is implemented by all datasources, which are able to return the active alerts.
+type AlertsClient interface {
+	GetAlerts(ctx context.Context, queries []string) ([]Alert, error)
 }
 
 func New(dir string) (map[string]Client, error) {
@@ -95,6 +157,22 @@ func newClient(datasource Datasource) (Client, error) {
 	switch datasource.Type {
 	case "Prometheus":
 		return NewPrometheusClient(datasource)
+	case "Loki":
+		return NewLokiClient(datasource)
+	case "InfluxDB":
+		return NewInfluxDBClient(datasource)
+	case "Elasticsearch":
+		return NewElasticsearchClient(datasource)
+	case "SQL":
+		return NewSQLClient(datasource)
+	case "Graphite":
+		return NewGraphiteClient(datasource)
+	case "JSON":
+		return NewJSONClient(datasource)
+	case "File":
+		return NewFileClient(datasource)
+	case "Alertmanager":
+		return NewAlertmanagerClient(datasource)
 	default:
 		return nil, ErrInvalidType
 	}
@@ -114,3 +192,269 @@ func QueryInterpolation(query string, variables map[string]string) (string, erro
 	return buf.String(), nil
 
 }
+
+// parseTime converts the value of a timestamp returned by a datasource. The value can be a time, a unix timestamp in
+// seconds or milliseconds or a string in one of the supported formats.
+func parseTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case time.Time:
+		return v, nil
+	case int64:
+		return parseUnixTime(float64(v)), nil
+	case float64:
+		return parseUnixTime(v), nil
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return parseUnixTime(f), nil
+		}
+
+		for _, layout := range timeLayouts {
+			if t, err := time.ParseInLocation(layout, v, time.UTC); err == nil {
+				return t, nil
+			}
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid timestamp: %v", value)
+}
+
+func parseUnixTime(value float64) time.Time {
+	if value > 1e12 {
+		return time.Unix(0, int64(value)*int64(time.Millisecond))
+	}
+
+	sec := int64(value)
+	return time.Unix(sec, int64((value-float64(sec))*1e9))
+}
+
+// parseValue converts the value of a point returned by a datasource. If the value can not be converted NaN is returned.
+func parseValue(value interface{}) float64 {
+	switch v := value.(type) {
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	default:
+		return math.NaN()
+	}
+}
+
+// alignSamples groups the given samples by their label and aligns the points of all series, so that each series
+// contains a point for each returned timestamp. Missing points are set to NaN. It returns the sorted timestamps, the
+// labels of the series in the order of their first occurrence and the points for each series.
+func alignSamples(samples []sample) ([]time.Time, []string, [][]float64) {
+	var labels []string
+	var nanos []int64
+	values := make(map[string]map[int64]float64)
+	seen := make(map[int64]bool)
+
+	for _, s := range samples {
+		if _, ok := values[s.Label]; !ok {
+			labels = append(labels, s.Label)
+			values[s.Label] = make(map[int64]float64)
+		}
+
+		nano := s.Timestamp.UnixNano()
+		if !seen[nano] {
+			seen[nano] = true
+			nanos = append(nanos, nano)
+		}
+
+		values[s.Label][nano] = s.Value
+	}
+
+	sort.Slice(nanos, func(i, j int) bool { return nanos[i] < nanos[j] })
+
+	var times []time.Time
+	for _, nano := range nanos {
+		times = append(times, time.Unix(0, nano))
+	}
+
+	var points [][]float64
+	for _, label := range labels {
+		var p []float64
+		for _, nano := range nanos {
+			if value, ok := values[label][nano]; ok {
+				p = append(p, value)
+			} else {
+				p = append(p, math.NaN())
+			}
+		}
+		points = append(points, p)
+	}
+
+	return times, labels, points
+}
+
+// MergeData merges the results of multiple datasources into one result. The timestamps of the first result which
+// contains points are used for the merged result. The series of all other results are aligned to these timestamps, by
+// using the last point of a series which lies between the previous and the current timestamp. If there is no such
+// point the value is NaN.
+func MergeData(results ...*Data) *Data {
+	var reference *Data
+	for _, result := range results {
+		if result != nil && len(result.Times) > 0 {
+			reference = result
+			break
+		}
+	}
+
+	merged := &Data{}
+
+	if reference != nil {
+		merged.Times = reference.Times
+	}
+
+	for _, result := range results {
+		if result == nil {
+			continue
+		}
+
+		if reference == nil || result == reference || equalTimes(result.Times, reference.Times) {
+			merged.Series = append(merged.Series, result.Series...)
+			continue
+		}
+
+		for _, series := range result.Series {
+			merged.Series = append(merged.Series, Series{
+				Label:     series.Label,
+				Labels:    series.Labels,
+				Points:    alignPoints(result.Times, series.Points, reference.Times),
+				TimeShift: series.TimeShift,
+			})
+		}
+	}
+
+	return merged
+}
+
+// alignPoints aligns the given points with the given times to the reference times. For each reference time the last
+// point after the previous reference time is used.
+func alignPoints(times []time.Time, points []float64, reference []time.Time) []float64 {
+	var aligned []float64
+	var index int
+
+	for key, t := range reference {
+		var lower time.Time
+		if key > 0 {
+			lower = reference[key-1]
+		} else if len(reference) > 1 {
+			lower = t.Add(-reference[1].Sub(reference[0]))
+		}
+
+		value := math.NaN()
+		for index < len(times) && index < len(points) && !times[index].After(t) {
+			if key == 0 && len(reference) == 1 || times[index].After(lower) {
+				value = points[index]
+			}
+			index++
+		}
+
+		aligned = append(aligned, value)
+	}
+
+	return aligned
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if !a[index].Equal(b[index]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// AppendData appends the points of tail to the points of previous and returns the result as new Data. All points of
+// previous which are not older than the first timestamp of tail are replaced by the points of tail and all points which
+// are older than start are dropped. The series are matched by their label. Series which are only contained in one of
+// both results are filled with NaN.
+func AppendData(previous, tail *Data, start time.Time) *Data {
+	cut := len(previous.Times)
+	if len(tail.Times) > 0 {
+		cut = sort.Search(len(previous.Times), func(i int) bool { return !previous.Times[i].Before(tail.Times[0]) })
+	}
+
+	first := sort.Search(cut, func(i int) bool { return !previous.Times[i].Before(start) })
+
+	data := &Data{}
+
+	data.Times = append(data.Times, previous.Times[first:cut]...)
+	data.Times = append(data.Times, tail.Times...)
+	// The label is not unique, so that the series are matched by their label and the number of previous series with
+	// the same label.
+	tailKeys := seriesKeys(tail.Series)
+	tailSeries := make(map[string]Series)
+	for index, key := range tailKeys {
+		tailSeries[key] = tail.Series[index]
+	}
+
+	for index, key := range seriesKeys(previous.Series) {
+		series := previous.Series[index]
+		points := pointsBetween(series.Points, first, cut)
+
+		if t, ok := tailSeries[key]; ok {
+			points = append(points, pointsBetween(t.Points, 0, len(tail.Times))...)
+			delete(tailSeries, key)
+		} else {
+			points = append(points, nanPoints(len(tail.Times))...)
+		}
+
+		data.Series = append(data.Series, Series{Label: series.Label, Labels: series.Labels, Points: points, TimeShift: series.TimeShift})
+	}
+
+	for _, key := range tailKeys {
+		if t, ok := tailSeries[key]; ok {
+			points := append(nanPoints(cut-first), pointsBetween(t.Points, 0, len(tail.Times))...)
+			data.Series = append(data.Series, Series{Label: t.Label, Labels: t.Labels, Points: points, TimeShift: t.TimeShift})
+		}
+	}
+
+	return data
+}
+
+func seriesKeys(series []Series) []string {
+	var keys []string
+	counts := make(map[string]int)
+
+	for _, s := range series {
+		keys = append(keys, fmt.Sprintf("%s|%d", s.Label, counts[s.Label]))
+		counts[s.Label]++
+	}
+
+	return keys
+}
+
+// pointsBetween returns a copy of the points between the start and end index. Missing points are filled with NaN.
+func pointsBetween(points []float64, start, end int) []float64 {
+	result := make([]float64, 0, end-start)
+	for index := start; index < end; index++ {
+		if index < len(points) {
+			result = append(result, points[index])
+		} else {
+			result = append(result, math.NaN())
+		}
+	}
+
+	return result
+}
+
+func nanPoints(count int) []float64 {
+	return pointsBetween(nil, 0, count)
+}
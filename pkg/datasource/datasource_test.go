@@ -0,0 +1,120 @@
+package datasource
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestAppendData(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	times := func(minutes ...int) []time.Time {
+		var result []time.Time
+		for _, minute := range minutes {
+			result = append(result, start.Add(time.Duration(minute)*time.Minute))
+		}
+		return result
+	}
+
+	nan := math.NaN()
+
+	for _, tc := range []struct {
+		name     string
+		previous *Data
+		tail     *Data
+		start    time.Time
+		expected *Data
+	}{
+		{
+			name:     "append",
+			previous: &Data{Times: times(0, 1, 2), Series: []Series{{Label: "a", Points: []float64{0, 1, 2}}}},
+			tail:     &Data{Times: times(3, 4), Series: []Series{{Label: "a", Points: []float64{3, 4}}}},
+			start:    start,
+			expected: &Data{Times: times(0, 1, 2, 3, 4), Series: []Series{{Label: "a", Points: []float64{0, 1, 2, 3, 4}}}},
+		},
+		{
+			name:     "replace overlapping points",
+			previous: &Data{Times: times(0, 1, 2), Series: []Series{{Label: "a", Points: []float64{0, 1, 2}}}},
+			tail:     &Data{Times: times(2, 3), Series: []Series{{Label: "a", Points: []float64{20, 3}}}},
+			start:    start,
+			expected: &Data{Times: times(0, 1, 2, 3), Series: []Series{{Label: "a", Points: []float64{0, 1, 20, 3}}}},
+		},
+		{
+			name:     "drop points before start",
+			previous: &Data{Times: times(0, 1, 2), Series: []Series{{Label: "a", Points: []float64{0, 1, 2}}}},
+			tail:     &Data{Times: times(3), Series: []Series{{Label: "a", Points: []float64{3}}}},
+			start:    start.Add(2 * time.Minute),
+			expected: &Data{Times: times(2, 3), Series: []Series{{Label: "a", Points: []float64{2, 3}}}},
+		},
+		{
+			name:     "empty tail",
+			previous: &Data{Times: times(0, 1), Series: []Series{{Label: "a", Points: []float64{0, 1}}}},
+			tail:     &Data{},
+			start:    start.Add(time.Minute),
+			expected: &Data{Times: times(1), Series: []Series{{Label: "a", Points: []float64{1}}}},
+		},
+		{
+			name:     "missing series",
+			previous: &Data{Times: times(0, 1), Series: []Series{{Label: "a", Points: []float64{0, 1}}}},
+			tail:     &Data{Times: times(2), Series: []Series{{Label: "b", Points: []float64{2}}}},
+			start:    start,
+			expected: &Data{Times: times(0, 1, 2), Series: []Series{
+				{Label: "a", Points: []float64{0, 1, nan}},
+				{Label: "b", Points: []float64{nan, nan, 2}},
+			}},
+		},
+		{
+			name: "duplicated labels",
+			previous: &Data{Times: times(0), Series: []Series{
+				{Label: "a", Points: []float64{0}},
+				{Label: "a", Points: []float64{10}},
+			}},
+			tail: &Data{Times: times(1), Series: []Series{
+				{Label: "a", Points: []float64{1}},
+				{Label: "a", Points: []float64{11}},
+			}},
+			start: start,
+			expected: &Data{Times: times(0, 1), Series: []Series{
+				{Label: "a", Points: []float64{0, 1}},
+				{Label: "a", Points: []float64{10, 11}},
+			}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			data := AppendData(tc.previous, tc.tail, tc.start)
+
+			if len(data.Times) != len(tc.expected.Times) {
+				t.Fatalf("expected times %v, got %v", tc.expected.Times, data.Times)
+			}
+			for index := range data.Times {
+				if !data.Times[index].Equal(tc.expected.Times[index]) {
+					t.Fatalf("expected times %v, got %v", tc.expected.Times, data.Times)
+				}
+			}
+
+			if len(data.Series) != len(tc.expected.Series) {
+				t.Fatalf("expected %d series, got %d", len(tc.expected.Series), len(data.Series))
+			}
+			for index, series := range data.Series {
+				expected := tc.expected.Series[index]
+				if series.Label != expected.Label || !equalPoints(series.Points, expected.Points) {
+					t.Errorf("expected series %s %v, got %s %v", expected.Label, expected.Points, series.Label, series.Points)
+				}
+			}
+		})
+	}
+}
+
+func equalPoints(a, b []float64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] && !(math.IsNaN(a[index]) && math.IsNaN(b[index])) {
+			return false
+		}
+	}
+
+	return true
+}
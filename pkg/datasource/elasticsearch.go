@@ -0,0 +1,518 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+var elasticsearchMetricRegexp = regexp.MustCompile(`^(count|avg|sum|min|max)(?:\(([^)]+)\))?(?:\s+by\s+(\S+))?$`)
+
+// Elasticsearch implements the Client interface for Elasticsearch and OpenSearch. A query has the following format:
+//
+//	<index pattern> | <lucene filter> | <metric>(<field>) by <field>
+//
+// The metric can be count, avg, sum, min or max. For all metrics except count a field is required. The "by" part is
+// optional and splits the result into multiple series by a terms aggregation. For example the query
+//
+//	access-* | status:>=500 | count by host.keyword
+//
+// returns the number of requests with a status code greater or equal 500 for each host.
+//
+// Queries for variables are using the format "<index pattern> | <lucene filter>" and the label of the variable is the
+// field for the terms aggregation.
+type Elasticsearch struct {
+	client    *http.Client
+	url       string
+	timeField string
+	options   Options
+}
+
+type elasticsearchQuery struct {
+	Index  string
+	Filter string
+	Metric string
+	Field  string
+	By     string
+}
+
+type elasticsearchBucket struct {
+	Key      interface{} `json:"key"`
+	DocCount float64     `json:"doc_count"`
+	Metric   *struct {
+		Value *float64 `json:"value"`
+	} `json:"metric"`
+	Histogram *struct {
+		Buckets []elasticsearchBucket `json:"buckets"`
+	} `json:"histogram"`
+}
+
+type elasticsearchResponse struct {
+	Aggregations struct {
+		Terms *struct {
+			Buckets []elasticsearchBucket `json:"buckets"`
+		} `json:"terms"`
+		Histogram *struct {
+			Buckets []elasticsearchBucket `json:"buckets"`
+		} `json:"histogram"`
+		Metric *struct {
+			Value *float64 `json:"value"`
+		} `json:"metric"`
+	} `json:"aggregations"`
+	Hits struct {
+		Total interface{} `json:"total"`
+	} `json:"hits"`
+}
+
+type elasticsearchErrorResponse struct {
+	Error struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+func NewElasticsearchClient(datasource Datasource) (*Elasticsearch, error) {
+	_, err := url.Parse(datasource.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	timeField := datasource.TimeField
+	if timeField == "" {
+		timeField = "@timestamp"
+	}
+
+	return &Elasticsearch{
+		client:    &http.Client{Transport: newRoundTripper(datasource.Auth)},
+		url:       strings.TrimSuffix(datasource.URL, "/"),
+		timeField: timeField,
+		options:   datasource.Options,
+	}, nil
+}
+
+func (e *Elasticsearch) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(e.options))
+	defer cancel()
+
+	q, err := parseElasticsearchQuery(query, false)
+	if err != nil {
+		return nil, err
+	}
+
+	body := map[string]interface{}{
+		"size":  0,
+		"query": e.filter(q, &start, &end),
+		"aggs": map[string]interface{}{
+			"terms": map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": label,
+					"size":  500,
+					"order": map[string]string{"_key": "asc"},
+				},
+			},
+		},
+	}
+
+	var res elasticsearchResponse
+	err = e.search(ctx, q.Index, body, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	if res.Aggregations.Terms != nil {
+		for _, bucket := range res.Aggregations.Terms.Buckets {
+			values = appendIfMissing(values, fmt.Sprintf("%v", bucket.Key))
+		}
+	}
+
+	return values, nil
+}
+
+func (e *Elasticsearch) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(e.options))
+	defer cancel()
+
+	var series []Series
+	var times []time.Time
+
+	timeRange := getTimeRange(ctx, e.options, start, end)
+
+	step := timeRange.Step
+	if step < time.Second {
+		step = time.Second
+	}
+	traceAligned(ctx, step)
+
+	for i, query := range queries {
+		q, err := parseElasticsearchQuery(query, true)
+		if err != nil {
+			return nil, err
+		}
+
+		histogram := map[string]interface{}{
+			"date_histogram": map[string]interface{}{
+				"field":          e.timeField,
+				"fixed_interval": fmt.Sprintf("%ds", int64(step.Seconds())),
+				"min_doc_count":  0,
+				"extended_bounds": map[string]int64{
+					"min": toMilliseconds(timeRange.Start),
+					"max": toMilliseconds(timeRange.End),
+				},
+			},
+		}
+		if aggregation := q.metricAggregation(); aggregation != nil {
+			histogram["aggs"] = map[string]interface{}{"metric": aggregation}
+		}
+
+		aggs := map[string]interface{}{"histogram": histogram}
+		if q.By != "" {
+			aggs = map[string]interface{}{
+				"terms": map[string]interface{}{
+					"terms": map[string]interface{}{"field": q.By, "size": 10},
+					"aggs":  aggs,
+				},
+			}
+		}
+
+		body := map[string]interface{}{
+			"size":  0,
+			"query": e.filter(q, &timeRange.Start, &timeRange.End),
+			"aggs":  aggs,
+		}
+
+		var res elasticsearchResponse
+		err = e.search(ctx, q.Index, body, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []elasticsearchBucket
+		if res.Aggregations.Terms != nil {
+			results = res.Aggregations.Terms.Buckets
+		} else if res.Aggregations.Histogram != nil {
+			results = []elasticsearchBucket{{Histogram: res.Aggregations.Histogram}}
+		}
+
+		for _, result := range results {
+			if result.Histogram == nil {
+				continue
+			}
+
+			returnedLabels := q.labels(result.Key)
+			fLog.Debugf("query %s returned %d points and the following labels %v", query, len(result.Histogram.Buckets), returnedLabels)
+
+			var points []float64
+
+			for _, bucket := range result.Histogram.Buckets {
+				if len(series) == 0 {
+					msec, ok := bucket.Key.(float64)
+					if !ok {
+						return nil, fmt.Errorf("invalid timestamp: %v", bucket.Key)
+					}
+					times = append(times, time.Unix(0, int64(msec)*int64(time.Millisecond)))
+				}
+				points = append(points, q.value(bucket))
+			}
+
+			series = append(series, Series{
+				Label:  getLabel(labels[i], returnedLabels),
+				Labels: returnedLabels,
+				Points: points,
+			})
+		}
+	}
+
+	return &Data{
+		Times:  times,
+		Series: series,
+	}, nil
+}
+
+// GetTableData runs a terms aggregation with the metric as sub aggregation for each query. In contrast to the GetData
+// function no time range is applied, so that the filter of the query should be used to restrict the time range, e.g.
+// "@timestamp:[now-1h TO now]".
+func (e *Elasticsearch) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(e.options))
+	defer cancel()
+
+	var tableData TableData
+	tableData = make(map[string]map[string]interface{})
+
+	for i, query := range queries {
+		q, err := parseElasticsearchQuery(query, true)
+		if err != nil {
+			return nil, err
+		}
+
+		body := map[string]interface{}{
+			"size":  0,
+			"query": e.filter(q, nil, nil),
+		}
+
+		aggregation := q.metricAggregation()
+		if q.By != "" {
+			terms := map[string]interface{}{
+				"terms": map[string]interface{}{"field": q.By, "size": 100},
+			}
+			if aggregation != nil {
+				terms["aggs"] = map[string]interface{}{"metric": aggregation}
+			}
+			body["aggs"] = map[string]interface{}{"terms": terms}
+		} else if aggregation != nil {
+			body["aggs"] = map[string]interface{}{"metric": aggregation}
+		}
+
+		var res elasticsearchResponse
+		err = e.search(ctx, q.Index, body, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		var results []elasticsearchBucket
+		if res.Aggregations.Terms != nil {
+			results = res.Aggregations.Terms.Buckets
+		} else {
+			results = []elasticsearchBucket{{DocCount: elasticsearchTotal(res.Hits.Total), Metric: res.Aggregations.Metric}}
+		}
+
+		for _, result := range results {
+			returnedLabels := q.labels(result.Key)
+
+			joinValue := getLabel(labels[i], returnedLabels)
+			for key, value := range returnedLabels {
+				if _, ok := tableData[joinValue]; !ok {
+					tableData[joinValue] = make(map[string]interface{})
+				}
+
+				if _, ok := tableData[joinValue][key]; !ok {
+					tableData[joinValue][key] = value
+				}
+
+				tableData[joinValue][fmt.Sprintf("value_%d", i)] = q.value(result)
+			}
+		}
+	}
+
+	return &tableData, nil
+}
+
+func (e *Elasticsearch) GetSuggestions(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(e.options))
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, e.url+"/_cat/indices?format=json&h=index", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var indices []struct {
+		Index string `json:"index"`
+	}
+
+	err = e.do(ctx, req, &indices)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, index := range indices {
+		values = appendIfMissing(values, index.Index)
+	}
+
+	return values, nil
+}
+
+// filter returns the query for the search request, which filters the documents by the lucene filter of the query and
+// the provided time range.
+func (e *Elasticsearch) filter(q elasticsearchQuery, start, end *time.Time) map[string]interface{} {
+	var filters []interface{}
+
+	if start != nil && end != nil {
+		filters = append(filters, map[string]interface{}{
+			"range": map[string]interface{}{
+				e.timeField: map[string]interface{}{
+					"gte":    toMilliseconds(*start),
+					"lte":    toMilliseconds(*end),
+					"format": "epoch_millis",
+				},
+			},
+		})
+	}
+
+	filters = append(filters, map[string]interface{}{
+		"query_string": map[string]interface{}{
+			"query":            q.Filter,
+			"analyze_wildcard": true,
+		},
+	})
+
+	return map[string]interface{}{
+		"bool": map[string]interface{}{
+			"filter": filters,
+		},
+	}
+}
+
+func (e *Elasticsearch) search(ctx context.Context, index string, body map[string]interface{}, v interface{}) error {
+	fLog.Debugf("search index %s: %v", index, body)
+
+	reqBody, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.url+"/"+url.PathEscape(index)+"/_search", bytes.NewReader(reqBody))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return e.do(ctx, req, v)
+}
+
+func (e *Elasticsearch) do(ctx context.Context, req *http.Request, v interface{}) error {
+	resp, err := e.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse elasticsearchErrorResponse
+		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Error.Reason != "" {
+			return fmt.Errorf("unexpected status code %d: %s: %s", resp.StatusCode, errorResponse.Error.Type, errorResponse.Error.Reason)
+		}
+
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// parseElasticsearchQuery parses the given query into its parts. If withMetric is false the query only consists of the
+// index pattern and the lucene filter, which is used for variables.
+func parseElasticsearchQuery(query string, withMetric bool) (elasticsearchQuery, error) {
+	var q elasticsearchQuery
+
+	parts := strings.SplitN(query, "|", 2)
+	q.Index = strings.TrimSpace(parts[0])
+	if q.Index == "" {
+		return q, fmt.Errorf("invalid query %s: index pattern is missing", query)
+	}
+
+	var rest string
+	if len(parts) == 2 {
+		rest = parts[1]
+	}
+
+	if withMetric {
+		// The lucene filter can contain the "||" operator, so that we are using the last "|" to split the filter and the
+		// metric. If the query only contains one "|" the filter is omitted.
+		metric := rest
+		if index := strings.LastIndex(rest, "|"); index != -1 {
+			metric = rest[index+1:]
+			rest = rest[:index]
+		} else {
+			rest = ""
+		}
+
+		matches := elasticsearchMetricRegexp.FindStringSubmatch(strings.TrimSpace(metric))
+		if matches == nil {
+			return q, fmt.Errorf("invalid query %s: invalid metric %s", query, strings.TrimSpace(metric))
+		}
+
+		q.Metric = matches[1]
+		q.Field = matches[2]
+		q.By = matches[3]
+
+		if q.Metric != "count" && q.Field == "" {
+			return q, fmt.Errorf("invalid query %s: metric %s requires a field", query, q.Metric)
+		}
+	}
+
+	q.Filter = strings.TrimSpace(rest)
+	if q.Filter == "" {
+		q.Filter = "*"
+	}
+
+	return q, nil
+}
+
+func (q elasticsearchQuery) metricAggregation() map[string]interface{} {
+	if q.Metric == "count" {
+		return nil
+	}
+
+	return map[string]interface{}{
+		q.Metric: map[string]interface{}{"field": q.Field},
+	}
+}
+
+func (q elasticsearchQuery) value(bucket elasticsearchBucket) float64 {
+	if q.Metric == "count" {
+		return bucket.DocCount
+	}
+
+	if bucket.Metric == nil || bucket.Metric.Value == nil {
+		return math.NaN()
+	}
+
+	return *bucket.Metric.Value
+}
+
+// labels returns the labels for a series, which can be used in the label template. The labels are the index pattern,
+// the metric and when the query contains a "by" part the key of the terms bucket as "term" and as the name of the
+// field.
+func (q elasticsearchQuery) labels(key interface{}) map[string]string {
+	metric := q.Metric
+	if q.Field != "" {
+		metric = fmt.Sprintf("%s(%s)", q.Metric, q.Field)
+	}
+
+	labels := map[string]string{
+		"index":  q.Index,
+		"metric": metric,
+	}
+
+	if q.By != "" && key != nil {
+		labels["term"] = fmt.Sprintf("%v", key)
+		labels[q.By] = labels["term"]
+	}
+
+	return labels
+}
+
+// elasticsearchTotal returns the total number of hits. Elasticsearch 7 and newer are returning an object, older
+// versions are returning a number.
+func elasticsearchTotal(total interface{}) float64 {
+	switch t := total.(type) {
+	case float64:
+		return t
+	case map[string]interface{}:
+		if value, ok := t["value"].(float64); ok {
+			return value
+		}
+	}
+
+	return 0
+}
+
+func toMilliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,250 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// decodeElasticsearchRequest decodes the body of a search request, so that the tests can check the aggregations.
+func decodeElasticsearchRequest(t *testing.T, r *http.Request) map[string]interface{} {
+	t.Helper()
+
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+	}
+
+	var body map[string]interface{}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	return body
+}
+
+func TestElasticsearchGetDataTerms(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577836860, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/access-*/_search" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		body := decodeElasticsearchRequest(t, r)
+		terms := body["aggs"].(map[string]interface{})["terms"].(map[string]interface{})
+		if field := terms["terms"].(map[string]interface{})["field"]; field != "host.keyword" {
+			t.Errorf("unexpected terms field %v", field)
+		}
+
+		histogram := terms["aggs"].(map[string]interface{})["histogram"].(map[string]interface{})
+		dateHistogram := histogram["date_histogram"].(map[string]interface{})
+		if dateHistogram["field"] != "timestamp" || dateHistogram["fixed_interval"] != "30s" {
+			t.Errorf("unexpected date histogram %v", dateHistogram)
+		}
+
+		metric := histogram["aggs"].(map[string]interface{})["metric"].(map[string]interface{})
+		if field := metric["avg"].(map[string]interface{})["field"]; field != "duration" {
+			t.Errorf("unexpected metric %v", metric)
+		}
+
+		fmt.Fprint(w, `{"aggregations":{"terms":{"buckets":[
+			{"key":"server-1","doc_count":3,"histogram":{"buckets":[
+				{"key":1577836800000,"doc_count":2,"metric":{"value":1.5}},
+				{"key":1577836830000,"doc_count":0,"metric":{"value":null}},
+				{"key":1577836860000,"doc_count":1,"metric":{"value":2}}
+			]}},
+			{"key":"server-2","doc_count":1,"histogram":{"buckets":[
+				{"key":1577836800000,"doc_count":0,"metric":{"value":null}},
+				{"key":1577836830000,"doc_count":1,"metric":{"value":3}},
+				{"key":1577836860000,"doc_count":0,"metric":{"value":null}}
+			]}}
+		]}}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewElasticsearchClient(Datasource{URL: server.URL, TimeField: "timestamp", Options: Options{Step: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := &Trace{}
+	data, err := client.GetData(WithTrace(context.Background(), trace), []string{"access-* | status:>=500 | avg(duration) by host.keyword"}, []string{"{{.term}} {{.metric}}"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trace.Step != 30*time.Second || !trace.Aligned {
+		t.Errorf("unexpected trace %v", trace)
+	}
+
+	if len(data.Times) != 3 || !data.Times[0].Equal(start) || !data.Times[2].Equal(end) {
+		t.Fatalf("unexpected times %v", data.Times)
+	}
+
+	if len(data.Series) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(data.Series))
+	}
+
+	if data.Series[0].Label != "server-1 avg(duration)" || data.Series[0].Labels["host.keyword"] != "server-1" || data.Series[0].Labels["index"] != "access-*" {
+		t.Errorf("unexpected series %v", data.Series[0])
+	}
+
+	if data.Series[0].Points[0] != 1.5 || !math.IsNaN(data.Series[0].Points[1]) || data.Series[0].Points[2] != 2 {
+		t.Errorf("unexpected points %v", data.Series[0].Points)
+	}
+
+	if data.Series[1].Label != "server-2 avg(duration)" || data.Series[1].Points[1] != 3 {
+		t.Errorf("unexpected series %v", data.Series[1])
+	}
+}
+
+func TestElasticsearchGetDataCount(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577836830, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := decodeElasticsearchRequest(t, r)
+		histogram := body["aggs"].(map[string]interface{})["histogram"].(map[string]interface{})
+		if _, ok := histogram["aggs"]; ok {
+			t.Errorf("unexpected metric aggregation for count %v", histogram)
+		}
+
+		fmt.Fprint(w, `{"aggregations":{"histogram":{"buckets":[
+			{"key":1577836800000,"doc_count":5},
+			{"key":1577836830000,"doc_count":7}
+		]}}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewElasticsearchClient(Datasource{URL: server.URL, Options: Options{Step: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := client.GetData(context.Background(), []string{"access-* | count"}, []string{"{{.metric}}"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Series) != 1 || data.Series[0].Label != "count" {
+		t.Fatalf("unexpected series %v", data.Series)
+	}
+
+	if data.Series[0].Points[0] != 5 || data.Series[0].Points[1] != 7 {
+		t.Errorf("unexpected points %v", data.Series[0].Points)
+	}
+}
+
+func TestElasticsearchGetDataError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"error":{"type":"search_phase_execution_exception","reason":"all shards failed"}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewElasticsearchClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetData(context.Background(), []string{"access-* | count"}, []string{""}, time.Unix(1577836800, 0), time.Unix(1577836860, 0))
+	if err == nil || err.Error() != "unexpected status code 400: search_phase_execution_exception: all shards failed" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestElasticsearchGetTableData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := decodeElasticsearchRequest(t, r)
+		if filter := fmt.Sprintf("%v", body["query"]); strings.Contains(filter, "range") {
+			t.Errorf("unexpected time range filter %s", filter)
+		}
+
+		aggs, ok := body["aggs"].(map[string]interface{})
+		if !ok {
+			fmt.Fprint(w, `{"hits":{"total":{"value":42,"relation":"eq"}}}`)
+			return
+		}
+
+		if _, ok := aggs["terms"]; ok {
+			fmt.Fprint(w, `{"aggregations":{"terms":{"buckets":[
+				{"key":"server-1","doc_count":3,"metric":{"value":120}},
+				{"key":"server-2","doc_count":1,"metric":{"value":80}}
+			]}}}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"hits":{"total":4},"aggregations":{"metric":{"value":200}}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewElasticsearchClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tableData, err := client.GetTableData(context.Background(), []string{"access-* | sum(bytes) by host"}, []string{"{{.host}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*tableData) != 2 || (*tableData)["server-1"]["value_0"] != 120.0 || (*tableData)["server-2"]["value_0"] != 80.0 {
+		t.Errorf("unexpected table data %v", *tableData)
+	}
+
+	if (*tableData)["server-1"]["metric"] != "sum(bytes)" {
+		t.Errorf("unexpected row %v", (*tableData)["server-1"])
+	}
+
+	tableData, err = client.GetTableData(context.Background(), []string{"access-* | count", "access-* | sum(bytes)"}, []string{"{{.index}}", "{{.index}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	row := (*tableData)["access-*"]
+	if row["value_0"] != 42.0 || row["value_1"] != 200.0 {
+		t.Errorf("unexpected row %v", row)
+	}
+}
+
+func TestParseElasticsearchQuery(t *testing.T) {
+	for _, tc := range []struct {
+		query      string
+		withMetric bool
+		expected   elasticsearchQuery
+		err        bool
+	}{
+		{query: "access-*", expected: elasticsearchQuery{Index: "access-*", Filter: "*"}},
+		{query: "access-* | status:200", expected: elasticsearchQuery{Index: "access-*", Filter: "status:200"}},
+		{query: "access-* | count", withMetric: true, expected: elasticsearchQuery{Index: "access-*", Filter: "*", Metric: "count"}},
+		{query: "access-* | status:500 || status:503 | max(duration) by host", withMetric: true, expected: elasticsearchQuery{Index: "access-*", Filter: "status:500 || status:503", Metric: "max", Field: "duration", By: "host"}},
+		{query: " | count", withMetric: true, err: true},
+		{query: "access-* | avg", withMetric: true, err: true},
+		{query: "access-* | median(duration)", withMetric: true, err: true},
+	} {
+		t.Run(tc.query, func(t *testing.T) {
+			q, err := parseElasticsearchQuery(tc.query, tc.withMetric)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", q)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if q != tc.expected {
+				t.Errorf("expected %v, got %v", tc.expected, q)
+			}
+		})
+	}
+}
@@ -0,0 +1,565 @@
+package datasource
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+const (
+	fileFormatCSV    = "csv"
+	fileFormatNDJSON = "ndjson"
+	fileMaxRecords   = 100000
+	fileFieldLabel   = "__name__"
+	fileKeySeparator = "\xff"
+)
+
+var (
+	fileTimeFields    = []string{"time", "timestamp", "ts"}
+	fileMatcherRegexp = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*"(.*)"\s*$`)
+)
+
+// File implements the Client interface for time series, which are read from a CSV or newline-delimited JSON file or
+// from stdin, when the url of the datasource is "-". The first line of a CSV file must contain the names of the
+// columns. A NDJSON file must contain one object per line.
+//
+// The column / key "time", "timestamp" or "ts" is used as timestamp for a record. If a record does not contain a
+// timestamp the time when the record was read is used. All other numeric columns / keys are fields, which can be
+// queried and all non numeric columns / keys are labels. A query selects the fields via a regular expression and can
+// filter the records by their labels, e.g. 'cpu_.*{host="{{.host}}", mode!="idle"}'.
+//
+// In file mode the file is tailed on each refresh, so that new lines are added to the time series. In stdin mode the
+// lines are read continuously in the background.
+type File struct {
+	mu      sync.Mutex
+	path    string
+	format  string
+	options Options
+	offset  int64
+	pending string
+	header  []string
+	records []fileRecord
+	err     error
+}
+
+type fileRecord struct {
+	Timestamp time.Time
+	Labels    map[string]string
+	Values    map[string]float64
+}
+
+type fileQuery struct {
+	field    *regexp.Regexp
+	matchers []fileMatcher
+}
+
+type fileMatcher struct {
+	name     string
+	operator string
+	value    string
+	regexp   *regexp.Regexp
+}
+
+func NewFileClient(datasource Datasource) (*File, error) {
+	format := strings.ToLower(datasource.Format)
+	if format == "" {
+		if strings.HasSuffix(datasource.URL, ".csv") {
+			format = fileFormatCSV
+		} else if strings.HasSuffix(datasource.URL, ".ndjson") || strings.HasSuffix(datasource.URL, ".jsonl") {
+			format = fileFormatNDJSON
+		}
+	}
+
+	if format != "" && format != fileFormatCSV && format != fileFormatNDJSON {
+		return nil, fmt.Errorf("invalid file format: %s", datasource.Format)
+	}
+
+	f := &File{
+		path:    datasource.URL,
+		format:  format,
+		options: datasource.Options,
+	}
+
+	if f.path == "-" {
+		go f.readStdin(os.Stdin)
+	} else if _, err := os.Stat(f.path); err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func (f *File) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	q, err := parseFileQuery(query)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, record := range records {
+		if record.Timestamp.Before(start) || record.Timestamp.After(end) || !q.matches(record) {
+			continue
+		}
+
+		if value, ok := record.Labels[label]; ok {
+			values = appendIfMissing(values, value)
+		}
+	}
+
+	sort.Strings(values)
+	return values, nil
+}
+
+func (f *File) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	records, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var series []Series
+	var times []time.Time
+
+	// The records are not written in a fixed step, so that we are aligning the timestamps of the records to the step
+	// of the datasource. If multiple records are within the same step the last one is used.
+	step := getTimeRange(ctx, f.options, start, end).Step
+	if step < time.Second {
+		step = time.Second
+	}
+	traceAligned(ctx, step)
+
+	for i, query := range queries {
+		q, err := parseFileQuery(query)
+		if err != nil {
+			return nil, err
+		}
+
+		var samples []sample
+		seriesLabels := make(map[string]map[string]string)
+
+		for _, record := range records {
+			if record.Timestamp.Before(start) || record.Timestamp.After(end) || !q.matches(record) {
+				continue
+			}
+
+			for field, value := range record.Values {
+				if !q.field.MatchString(field) {
+					continue
+				}
+
+				key, returnedLabels := fileSeriesKey(field, record.Labels)
+				seriesLabels[key] = returnedLabels
+
+				samples = append(samples, sample{
+					Label:     key,
+					Timestamp: record.Timestamp.Truncate(step),
+					Value:     value,
+				})
+			}
+		}
+
+		alignedTimes, keys, points := alignSamples(samples)
+
+		for index, key := range keys {
+			fLog.Debugf("query %s returned %d points and the following labels %v", query, len(points[index]), seriesLabels[key])
+
+			if len(series) == 0 {
+				times = append(times, alignedTimes...)
+			}
+
+			series = append(series, Series{
+				Label:  getLabel(labels[i], seriesLabels[key]),
+				Labels: seriesLabels[key],
+				Points: points[index],
+			})
+		}
+	}
+
+	return &Data{
+		Times:  times,
+		Series: series,
+	}, nil
+}
+
+func (f *File) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	records, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var tableData TableData
+	tableData = make(map[string]map[string]interface{})
+
+	for i, query := range queries {
+		q, err := parseFileQuery(query)
+		if err != nil {
+			return nil, err
+		}
+
+		// The records are sorted by the time they were read, so that the last matching record of a series contains
+		// the current value.
+		for _, record := range records {
+			if !q.matches(record) {
+				continue
+			}
+
+			for field, value := range record.Values {
+				if !q.field.MatchString(field) {
+					continue
+				}
+
+				_, returnedLabels := fileSeriesKey(field, record.Labels)
+
+				joinValue := getLabel(labels[i], returnedLabels)
+				for key, label := range returnedLabels {
+					if _, ok := tableData[joinValue]; !ok {
+						tableData[joinValue] = make(map[string]interface{})
+					}
+
+					if _, ok := tableData[joinValue][key]; !ok {
+						tableData[joinValue][key] = label
+					}
+
+					tableData[joinValue][fmt.Sprintf("value_%d", i)] = value
+				}
+			}
+		}
+	}
+
+	return &tableData, nil
+}
+
+func (f *File) GetSuggestions(ctx context.Context) ([]string, error) {
+	records, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, record := range records {
+		for field := range record.Values {
+			values = appendIfMissing(values, field)
+		}
+	}
+
+	sort.Strings(values)
+	return values, nil
+}
+
+// read returns all records, which were read so far. In file mode all lines which were added to the file since the last
+// call are read before. If the file was truncated (e.g. by a log rotation) the file is read from the beginning.
+func (f *File) read() ([]fileRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.path == "-" {
+		return f.records, f.err
+	}
+
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Size() < f.offset {
+		fLog.Debugf("file %s was truncated, read it from the beginning", f.path)
+		f.offset = 0
+		f.pending = ""
+		f.header = nil
+		f.records = nil
+	}
+
+	_, err = file.Seek(f.offset, io.SeekStart)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		return nil, err
+	}
+	f.offset = f.offset + int64(len(data))
+
+	// Only complete lines are parsed. The last line is kept until the next read, when it does not end with a newline,
+	// because it could be written partially.
+	lines := strings.Split(f.pending+string(data), "\n")
+	f.pending = lines[len(lines)-1]
+
+	// Lines which can not be parsed are skipped like in stdin mode, because the offset was already moved behind them and
+	// returning an error would also drop all following lines.
+	for _, line := range lines[:len(lines)-1] {
+		err := f.parseLine(line)
+		if err != nil {
+			fLog.Debugf("could not parse line from file %s: %s", f.path, err.Error())
+		}
+	}
+
+	return f.records, nil
+}
+
+func (f *File) readStdin(reader io.Reader) {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		f.mu.Lock()
+		err := f.parseLine(scanner.Text())
+		if err != nil {
+			fLog.Debugf("could not parse line from stdin: %s", err.Error())
+		}
+		f.mu.Unlock()
+	}
+
+	if err := scanner.Err(); err != nil {
+		f.mu.Lock()
+		f.err = err
+		f.mu.Unlock()
+	}
+}
+
+// parseLine parses a single line and adds the record to the list of records. The caller must hold the lock.
+func (f *File) parseLine(line string) error {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	format := f.format
+	if format == "" {
+		format = fileFormatCSV
+		if strings.HasPrefix(strings.TrimSpace(line), "{") {
+			format = fileFormatNDJSON
+		}
+	}
+
+	var record fileRecord
+	var err error
+
+	if format == fileFormatNDJSON {
+		record, err = parseNDJSONRecord(line)
+	} else {
+		var fields []string
+		fields, err = csv.NewReader(strings.NewReader(line)).Read()
+		if err != nil {
+			return err
+		}
+
+		if f.header == nil {
+			f.header = fields
+			return nil
+		}
+
+		record, err = parseCSVRecord(f.header, fields)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	f.records = append(f.records, record)
+	if len(f.records) > fileMaxRecords {
+		f.records = f.records[len(f.records)-fileMaxRecords:]
+	}
+
+	return nil
+}
+
+func parseCSVRecord(header, fields []string) (fileRecord, error) {
+	record := fileRecord{
+		Timestamp: time.Now(),
+		Labels:    make(map[string]string),
+		Values:    make(map[string]float64),
+	}
+
+	for index, name := range header {
+		if index >= len(fields) {
+			break
+		}
+
+		name = strings.TrimSpace(name)
+		field := strings.TrimSpace(fields[index])
+
+		if isFileTimeField(name) {
+			timestamp, err := parseTime(field)
+			if err != nil {
+				return record, err
+			}
+			record.Timestamp = timestamp
+		} else if value, err := strconv.ParseFloat(field, 64); err == nil {
+			record.Values[name] = value
+		} else {
+			record.Labels[name] = field
+		}
+	}
+
+	return record, nil
+}
+
+func parseNDJSONRecord(line string) (fileRecord, error) {
+	record := fileRecord{
+		Timestamp: time.Now(),
+		Labels:    make(map[string]string),
+		Values:    make(map[string]float64),
+	}
+
+	var object map[string]interface{}
+	err := json.Unmarshal([]byte(line), &object)
+	if err != nil {
+		return record, err
+	}
+
+	for name, value := range object {
+		if isFileTimeField(name) {
+			timestamp, err := parseTime(value)
+			if err != nil {
+				return record, err
+			}
+			record.Timestamp = timestamp
+			continue
+		}
+
+		switch v := value.(type) {
+		case float64, bool:
+			record.Values[name] = parseValue(v)
+		case string:
+			record.Labels[name] = v
+		}
+	}
+
+	return record, nil
+}
+
+// parseFileQuery parses a query in the format 'field{label="value", label=~"regexp"}'. The field is a regular
+// expression and the label matchers are optional.
+func parseFileQuery(query string) (fileQuery, error) {
+	var q fileQuery
+
+	query = strings.TrimSpace(query)
+	field := query
+	var matchers string
+
+	if index := strings.Index(query, "{"); index != -1 {
+		if !strings.HasSuffix(query, "}") {
+			return q, fmt.Errorf("invalid query %s: missing }", query)
+		}
+
+		field = strings.TrimSpace(query[:index])
+		matchers = query[index+1 : len(query)-1]
+	}
+
+	if field == "" {
+		field = ".*"
+	}
+
+	fieldRegexp, err := regexp.Compile("^(?:" + field + ")$")
+	if err != nil {
+		return q, err
+	}
+	q.field = fieldRegexp
+
+	if strings.TrimSpace(matchers) == "" {
+		return q, nil
+	}
+
+	for _, matcher := range strings.Split(matchers, ",") {
+		parts := fileMatcherRegexp.FindStringSubmatch(matcher)
+		if parts == nil {
+			return q, fmt.Errorf("invalid query %s: invalid matcher %s", query, strings.TrimSpace(matcher))
+		}
+
+		m := fileMatcher{name: parts[1], operator: parts[2], value: parts[3]}
+		if m.operator == "=~" || m.operator == "!~" {
+			m.regexp, err = regexp.Compile("^(?:" + m.value + ")$")
+			if err != nil {
+				return q, err
+			}
+		}
+
+		q.matchers = append(q.matchers, m)
+	}
+
+	return q, nil
+}
+
+func (q fileQuery) matches(record fileRecord) bool {
+	for _, m := range q.matchers {
+		value := record.Labels[m.name]
+
+		switch m.operator {
+		case "=":
+			if value != m.value {
+				return false
+			}
+		case "!=":
+			if value == m.value {
+				return false
+			}
+		case "=~":
+			if !m.regexp.MatchString(value) {
+				return false
+			}
+		case "!~":
+			if m.regexp.MatchString(value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// fileSeriesKey returns a unique key for the series of the given field and labels and the labels for the label
+// template, which contains the name of the field as "__name__". The parts of the key are separated by a byte, which is
+// not valid in UTF-8 encoded data, so that different fields and labels can not result in the same key. A label named
+// "__name__" is overwritten by the name of the field.
+func fileSeriesKey(field string, labels map[string]string) (string, map[string]string) {
+	returnedLabels := make(map[string]string)
+	var keys []string
+
+	for name, value := range labels {
+		if name == fileFieldLabel {
+			continue
+		}
+
+		returnedLabels[name] = value
+		keys = append(keys, name+fileKeySeparator+value)
+	}
+
+	returnedLabels[fileFieldLabel] = field
+
+	sort.Strings(keys)
+	return strings.Join(append([]string{field}, keys...), fileKeySeparator), returnedLabels
+}
+
+func isFileTimeField(name string) bool {
+	for _, field := range fileTimeFields {
+		if name == field {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,71 @@
+package datasource
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileGetDataSkipsInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "metrics.ndjson")
+	lines := `{"time": "2020-01-01T00:00:00Z", "field": "a", "value": 1}
+not a valid line
+{"time": "2020-01-01T00:01:00Z", "field": "b", "value": 2}
+`
+
+	if err := ioutil.WriteFile(path, []byte(lines), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewFileClient(Datasource{URL: path, Options: Options{Step: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	data, err := client.GetData(context.Background(), []string{"value"}, []string{"{{.__name__}} {{.field}}"}, start, start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Both valid lines must be returned and the real "field" label must not be overwritten by the name of the field.
+	if len(data.Series) != 2 || data.Series[0].Label != "value a" || data.Series[1].Label != "value b" {
+		t.Fatalf("unexpected series %v", data.Series)
+	}
+
+	// Lines which are appended later must be read on the next call.
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(`{"time": "2020-01-01T00:01:00Z", "field": "c", "value": 3}` + "\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err = client.GetData(context.Background(), []string{"value"}, []string{"{{.field}}"}, start, start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Series) != 3 {
+		t.Fatalf("expected 3 series, got %d", len(data.Series))
+	}
+}
+
+func TestFileSeriesKey(t *testing.T) {
+	key1, _ := fileSeriesKey("a", map[string]string{"b": "c,d=e"})
+	key2, _ := fileSeriesKey("a", map[string]string{"b": "c", "d": "e"})
+	if key1 == key2 {
+		t.Errorf("expected different keys, got %q", key1)
+	}
+
+	_, labels := fileSeriesKey("value", map[string]string{"field": "a", "__name__": "b"})
+	if labels["field"] != "a" || labels["__name__"] != "value" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+}
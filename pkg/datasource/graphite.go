@@ -0,0 +1,294 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+const (
+	graphiteMaxSuggestions = 500
+)
+
+// Graphite implements the Client interface for Graphite. The labels of a series are the name of the returned target
+// ("target"), each node of the target name ("node0", "node1", ...) and the tags of the series. For example the target
+// "servers.web01.cpu.user" can be labeled via "{{.node1}}" as "web01".
+//
+// Queries for variables are using the /metrics/find API, so that a query like "servers.*" returns all servers. The
+// label of the variable can be set to "id" to use the full path of a node instead of the last part.
+type Graphite struct {
+	client  *http.Client
+	url     string
+	options Options
+}
+
+type graphiteSeries struct {
+	Target     string            `json:"target"`
+	Tags       map[string]string `json:"tags"`
+	Datapoints [][2]*float64     `json:"datapoints"`
+}
+
+type graphiteNode struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+	Leaf int    `json:"leaf"`
+}
+
+func NewGraphiteClient(datasource Datasource) (*Graphite, error) {
+	_, err := url.Parse(datasource.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Graphite{
+		client:  &http.Client{Transport: newRoundTripper(datasource.Auth)},
+		url:     strings.TrimSuffix(datasource.URL, "/"),
+		options: datasource.Options,
+	}, nil
+}
+
+func (g *Graphite) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(g.options))
+	defer cancel()
+
+	nodes, err := g.find(ctx, query, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, node := range nodes {
+		if label == "id" {
+			values = appendIfMissing(values, node.ID)
+		} else {
+			values = appendIfMissing(values, node.Text)
+		}
+	}
+
+	return values, nil
+}
+
+func (g *Graphite) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(g.options))
+	defer cancel()
+
+	var series []Series
+	var times []time.Time
+
+	timeRange := getTimeRange(ctx, g.options, start, end)
+
+	// Graphite does not support a step for the render API. Instead we are using the maxDataPoints parameter, which
+	// consolidates the returned data points, so that the result contains not more points then the calculated step
+	// allows.
+	var maxDataPoints int64
+	if timeRange.Step >= time.Second {
+		maxDataPoints = int64(timeRange.End.Sub(timeRange.Start) / timeRange.Step)
+	}
+
+	for i, query := range queries {
+		result, err := g.render(ctx, query, timeRange.Start, timeRange.End, maxDataPoints)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range result {
+			returnedLabels := graphiteLabels(d)
+			fLog.Debugf("query %s returned %d points and the following labels %v", query, len(d.Datapoints), returnedLabels)
+
+			var points []float64
+
+			for _, datapoint := range d.Datapoints {
+				if len(series) == 0 && datapoint[1] != nil {
+					times = append(times, time.Unix(int64(*datapoint[1]), 0))
+				}
+
+				if datapoint[0] == nil {
+					points = append(points, math.NaN())
+				} else {
+					points = append(points, *datapoint[0])
+				}
+			}
+
+			series = append(series, Series{
+				Label:  getGraphiteLabel(labels[i], d.Target, returnedLabels),
+				Labels: returnedLabels,
+				Points: points,
+			})
+		}
+	}
+
+	return &Data{
+		Times:  times,
+		Series: series,
+	}, nil
+}
+
+func (g *Graphite) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(g.options))
+	defer cancel()
+
+	var tableData TableData
+	tableData = make(map[string]map[string]interface{})
+
+	// The table shows the last value of each returned target.
+	start, end := getLookbackRange()
+
+	for i, query := range queries {
+		result, err := g.render(ctx, query, start, end, 0)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range result {
+			value := math.NaN()
+			for _, datapoint := range d.Datapoints {
+				if datapoint[0] != nil {
+					value = *datapoint[0]
+				}
+			}
+
+			returnedLabels := graphiteLabels(d)
+
+			joinValue := getGraphiteLabel(labels[i], d.Target, returnedLabels)
+			for key, label := range returnedLabels {
+				if _, ok := tableData[joinValue]; !ok {
+					tableData[joinValue] = make(map[string]interface{})
+				}
+
+				if _, ok := tableData[joinValue][key]; !ok {
+					tableData[joinValue][key] = label
+				}
+
+				tableData[joinValue][fmt.Sprintf("value_%d", i)] = value
+			}
+		}
+	}
+
+	return &tableData, nil
+}
+
+// GetSuggestions returns the paths of all metrics, which are found by walking down the metrics tree via the
+// /metrics/find API. To not overload the Graphite server the number of suggestions is limited.
+func (g *Graphite) GetSuggestions(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(g.options))
+	defer cancel()
+
+	end := time.Now()
+	start := end.Add(-24 * time.Hour)
+
+	var values []string
+	queue := []string{"*"}
+
+	for len(queue) > 0 && len(values) < graphiteMaxSuggestions {
+		query := queue[0]
+		queue = queue[1:]
+
+		nodes, err := g.find(ctx, query, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes {
+			if node.Leaf == 1 {
+				values = appendIfMissing(values, node.ID)
+			} else {
+				queue = append(queue, node.ID+".*")
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func (g *Graphite) render(ctx context.Context, target string, start, end time.Time, maxDataPoints int64) ([]graphiteSeries, error) {
+	params := url.Values{}
+	params.Set("target", target)
+	params.Set("from", strconv.FormatInt(start.Unix(), 10))
+	params.Set("until", strconv.FormatInt(end.Unix(), 10))
+	params.Set("format", "json")
+	if maxDataPoints > 0 {
+		params.Set("maxDataPoints", strconv.FormatInt(maxDataPoints, 10))
+	}
+
+	var result []graphiteSeries
+	err := g.get(ctx, "/render", params, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+func (g *Graphite) find(ctx context.Context, query string, start, end time.Time) ([]graphiteNode, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("from", strconv.FormatInt(start.Unix(), 10))
+	params.Set("until", strconv.FormatInt(end.Unix(), 10))
+
+	var nodes []graphiteNode
+	err := g.get(ctx, "/metrics/find", params, &nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes, nil
+}
+
+func (g *Graphite) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, g.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := g.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func graphiteLabels(series graphiteSeries) map[string]string {
+	var labels map[string]string
+	labels = make(map[string]string)
+
+	for key, value := range series.Tags {
+		labels[key] = value
+	}
+
+	for index, node := range strings.Split(series.Target, ".") {
+		labels[fmt.Sprintf("node%d", index)] = node
+	}
+
+	labels["target"] = series.Target
+	return labels
+}
+
+// getGraphiteLabel returns the target name as label, when no label template is provided. This is different from the
+// other datasources, because the target name is already a good label and much shorter then all nodes and tags.
+func getGraphiteLabel(label, target string, labels map[string]string) string {
+	if label == "" {
+		return target
+	}
+
+	return getLabel(label, labels)
+}
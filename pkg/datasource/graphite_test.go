@@ -0,0 +1,142 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGraphiteGetData(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577837400, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/render" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		query := r.URL.Query()
+		if query.Get("target") != "servers.*.cpu.user" || query.Get("from") != "1577836800" || query.Get("until") != "1577837400" || query.Get("format") != "json" {
+			t.Errorf("unexpected parameters %v", query)
+		}
+
+		// The time range of ten minutes with a step of one minute must be consolidated to ten data points.
+		if maxDataPoints := query.Get("maxDataPoints"); maxDataPoints != "10" {
+			t.Errorf("unexpected maxDataPoints %s", maxDataPoints)
+		}
+
+		fmt.Fprint(w, `[
+			{"target":"servers.web01.cpu.user","tags":{"name":"servers.web01.cpu.user","dc":"eu"},"datapoints":[[1.5,1577836800],[null,1577837100]]},
+			{"target":"servers.web02.cpu.user","tags":{},"datapoints":[[2,1577836800],[3,1577837100]]}
+		]`)
+	}))
+	defer server.Close()
+
+	client, err := NewGraphiteClient(Datasource{URL: server.URL + "/", Options: Options{Step: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := &Trace{}
+	data, err := client.GetData(WithTrace(context.Background(), trace), []string{"servers.*.cpu.user"}, []string{"{{.node1}}"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Graphite is consolidating the data points, so that the points are not aligned to the step.
+	if trace.Aligned {
+		t.Errorf("unexpected aligned trace %v", trace)
+	}
+
+	if len(data.Times) != 2 || !data.Times[0].Equal(start) || !data.Times[1].Equal(start.Add(5*time.Minute)) {
+		t.Fatalf("unexpected times %v", data.Times)
+	}
+
+	if len(data.Series) != 2 || data.Series[0].Label != "web01" || data.Series[1].Label != "web02" {
+		t.Fatalf("unexpected series %v", data.Series)
+	}
+
+	if data.Series[0].Labels["dc"] != "eu" || data.Series[0].Labels["node2"] != "cpu" || data.Series[0].Labels["target"] != "servers.web01.cpu.user" {
+		t.Errorf("unexpected labels %v", data.Series[0].Labels)
+	}
+
+	if data.Series[0].Points[0] != 1.5 || !math.IsNaN(data.Series[0].Points[1]) {
+		t.Errorf("unexpected points %v", data.Series[0].Points)
+	}
+}
+
+func TestGraphiteGetDataError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, "invalid target\n")
+	}))
+	defer server.Close()
+
+	client, err := NewGraphiteClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetData(context.Background(), []string{"servers.*("}, []string{""}, time.Unix(1577836800, 0), time.Unix(1577837400, 0))
+	if err == nil || err.Error() != "unexpected status code 400: invalid target" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestGraphiteGetTableData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if _, ok := query["maxDataPoints"]; ok {
+			t.Errorf("unexpected maxDataPoints %s", query.Get("maxDataPoints"))
+		}
+
+		from, _ := strconv.ParseInt(query.Get("from"), 10, 64)
+		until, _ := strconv.ParseInt(query.Get("until"), 10, 64)
+		if until-from != 300 {
+			t.Errorf("unexpected time range from %d until %d", from, until)
+		}
+
+		switch query.Get("target") {
+		case "servers.*.cpu.user":
+			fmt.Fprint(w, `[
+				{"target":"servers.web01.cpu.user","datapoints":[[1,1577836800],[2,1577836860],[null,1577836920]]},
+				{"target":"servers.web02.cpu.user","datapoints":[[null,1577836800],[null,1577836860]]}
+			]`)
+		case "servers.*.cpu.system":
+			fmt.Fprint(w, `[{"target":"servers.web01.cpu.system","datapoints":[[5,1577836800]]}]`)
+		default:
+			t.Errorf("unexpected target %s", query.Get("target"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewGraphiteClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tableData, err := client.GetTableData(context.Background(), []string{"servers.*.cpu.user", "servers.*.cpu.system"}, []string{"{{.node1}}", "{{.node1}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*tableData) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(*tableData))
+	}
+
+	// The table must contain the last value, which is not null.
+	web01 := (*tableData)["web01"]
+	if web01["value_0"] != 2.0 || web01["value_1"] != 5.0 || web01["target"] != "servers.web01.cpu.user" {
+		t.Errorf("unexpected row %v", web01)
+	}
+
+	web02 := (*tableData)["web02"]
+	if value, ok := web02["value_0"].(float64); !ok || !math.IsNaN(value) {
+		t.Errorf("unexpected row %v", web02)
+	}
+}
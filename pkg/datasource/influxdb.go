@@ -0,0 +1,567 @@
+package datasource
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+const (
+	influxDBLanguageInfluxQL = "influxql"
+	influxDBLanguageFlux     = "flux"
+)
+
+// InfluxDB implements the Client interface for InfluxDB. The queries can be written in InfluxQL (default) or in Flux,
+// which is selected via the language field of the datasource. Within a query the following macros can be used:
+//
+//	$__timeFilter: Is replaced with the time range of the dashboard (only InfluxQL), e.g. time >= 1577836800000ms and time <= 1577840400000ms
+//	$__timeFrom:   Is replaced with the start time of the dashboard, e.g. 1577836800000ms (InfluxQL) or 2020-01-01T00:00:00Z (Flux)
+//	$__timeTo:     Is replaced with the end time of the dashboard, e.g. 1577840400000ms (InfluxQL) or 2020-01-01T01:00:00Z (Flux)
+//	$__interval:   Is replaced with the step calculated from the step / maxPoints option of the datasource, e.g. 10s
+type InfluxDB struct {
+	client       *http.Client
+	url          string
+	database     string
+	organization string
+	language     string
+	token        string
+	options      Options
+}
+
+type influxDBTable struct {
+	Name    string
+	Tags    map[string]string
+	Columns []string
+	Rows    [][]interface{}
+}
+
+type influxDBResponse struct {
+	Results []struct {
+		Series []struct {
+			Name    string            `json:"name"`
+			Tags    map[string]string `json:"tags"`
+			Columns []string          `json:"columns"`
+			Values  [][]interface{}   `json:"values"`
+		} `json:"series"`
+		Error string `json:"error"`
+	} `json:"results"`
+	Error string `json:"error"`
+}
+
+type influxDBErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func NewInfluxDBClient(datasource Datasource) (*InfluxDB, error) {
+	_, err := url.Parse(datasource.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	language := strings.ToLower(datasource.Language)
+	if language == "" {
+		language = influxDBLanguageInfluxQL
+	}
+
+	if language != influxDBLanguageInfluxQL && language != influxDBLanguageFlux {
+		return nil, fmt.Errorf("invalid query language: %s", datasource.Language)
+	}
+
+	// InfluxDB expects the token in the "Authorization: Token <token>" header instead of the Bearer token used by
+	// Prometheus. Therefore we do not pass the token to the round tripper and set the header for each request.
+	auth := datasource.Auth
+	token := auth.Token
+	auth.Token = ""
+
+	return &InfluxDB{
+		client:       &http.Client{Transport: newRoundTripper(auth)},
+		url:          strings.TrimSuffix(datasource.URL, "/"),
+		database:     datasource.Database,
+		organization: datasource.Organization,
+		language:     language,
+		token:        token,
+		options:      datasource.Options,
+	}, nil
+}
+
+func (i *InfluxDB) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(i.options))
+	defer cancel()
+
+	tables, err := i.query(ctx, i.replaceMacros(query, start, end, getTimeRange(ctx, i.options, start, end).Step))
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+
+	for _, table := range tables {
+		if value, ok := table.Tags[label]; ok {
+			values = appendIfMissing(values, value)
+			continue
+		}
+
+		column := columnIndex(table.Columns, label)
+		if column == -1 {
+			// If the label is not a column of the result, we are using the value column. This allows queries like
+			// 'SHOW TAG VALUES WITH KEY = "host"' (InfluxQL) or 'schema.tagValues(bucket: "telegraf", tag: "host")'
+			// (Flux), which are returning the tag values in the value column.
+			column = columnIndex(table.Columns, "value")
+			if i.language == influxDBLanguageFlux {
+				column = columnIndex(table.Columns, "_value")
+			}
+		}
+
+		if column == -1 {
+			continue
+		}
+
+		for _, row := range table.Rows {
+			if column < len(row) && row[column] != nil {
+				values = appendIfMissing(values, fmt.Sprintf("%v", row[column]))
+			}
+		}
+	}
+
+	return values, nil
+}
+
+func (i *InfluxDB) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(i.options))
+	defer cancel()
+
+	var series []Series
+	var times []time.Time
+
+	timeRange := getTimeRange(ctx, i.options, start, end)
+
+	for index, query := range queries {
+		tables, err := i.query(ctx, i.replaceMacros(query, timeRange.Start, timeRange.End, timeRange.Step))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, table := range tables {
+			timeColumn, valueColumns := i.columns(table)
+			if timeColumn == -1 {
+				return nil, fmt.Errorf("query %s did not return a time column", query)
+			}
+
+			for _, valueColumn := range valueColumns {
+				returnedLabels := i.labels(table, valueColumn)
+				fLog.Debugf("query %s returned %d points and the following labels %v", query, len(table.Rows), returnedLabels)
+
+				var points []float64
+
+				for _, row := range table.Rows {
+					if len(series) == 0 {
+						timestamp, err := parseInfluxDBTime(row[timeColumn])
+						if err != nil {
+							return nil, err
+						}
+						times = append(times, timestamp)
+					}
+					points = append(points, parseInfluxDBValue(row[valueColumn]))
+				}
+
+				series = append(series, Series{
+					Label:  getLabel(labels[index], returnedLabels),
+					Labels: returnedLabels,
+					Points: points,
+				})
+			}
+		}
+	}
+
+	return &Data{
+		Times:  times,
+		Series: series,
+	}, nil
+}
+
+func (i *InfluxDB) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(i.options))
+	defer cancel()
+
+	var tableData TableData
+	tableData = make(map[string]map[string]interface{})
+
+	// Table data is an instant query in the other datasources, so that we are only using the last row of each returned
+	// series.
+	start, end := getLookbackRange()
+
+	for index, query := range queries {
+		tables, err := i.query(ctx, i.replaceMacros(query, start, end, getTimeRange(ctx, i.options, start, end).Step))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, table := range tables {
+			if len(table.Rows) == 0 {
+				continue
+			}
+
+			_, valueColumns := i.columns(table)
+			if len(valueColumns) == 0 {
+				continue
+			}
+
+			row := table.Rows[len(table.Rows)-1]
+			returnedLabels := i.labels(table, valueColumns[0])
+
+			joinValue := getLabel(labels[index], returnedLabels)
+			for key, value := range returnedLabels {
+				if _, ok := tableData[joinValue]; !ok {
+					tableData[joinValue] = make(map[string]interface{})
+				}
+
+				if _, ok := tableData[joinValue][key]; !ok {
+					tableData[joinValue][key] = value
+				}
+
+				tableData[joinValue][fmt.Sprintf("value_%d", index)] = parseInfluxDBValue(row[valueColumns[0]])
+			}
+		}
+	}
+
+	return &tableData, nil
+}
+
+func (i *InfluxDB) GetSuggestions(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(i.options))
+	defer cancel()
+
+	query := "SHOW MEASUREMENTS"
+	label := "name"
+	if i.language == influxDBLanguageFlux {
+		query = fmt.Sprintf("import \"influxdata/influxdb/schema\"\nschema.measurements(bucket: %q)", i.database)
+		label = "_value"
+	}
+
+	tables, err := i.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, table := range tables {
+		column := columnIndex(table.Columns, label)
+		if column == -1 {
+			continue
+		}
+
+		for _, row := range table.Rows {
+			values = appendIfMissing(values, fmt.Sprintf("%v", row[column]))
+		}
+	}
+
+	return values, nil
+}
+
+// replaceMacros replaces the supported macros in the given query with the values for the provided time range.
+func (i *InfluxDB) replaceMacros(query string, start, end time.Time, step time.Duration) string {
+	if step < time.Second {
+		step = time.Second
+	}
+
+	interval := fmt.Sprintf("%ds", int64(step.Seconds()))
+
+	if i.language == influxDBLanguageFlux {
+		return strings.NewReplacer(
+			"$__timeFrom", start.UTC().Format(time.RFC3339),
+			"$__timeTo", end.UTC().Format(time.RFC3339),
+			"$__interval", interval,
+		).Replace(query)
+	}
+
+	from := fmt.Sprintf("%dms", toMilliseconds(start))
+	to := fmt.Sprintf("%dms", toMilliseconds(end))
+
+	return strings.NewReplacer(
+		"$__timeFilter", fmt.Sprintf("time >= %s and time <= %s", from, to),
+		"$__timeFrom", from,
+		"$__timeTo", to,
+		"$__interval", interval,
+	).Replace(query)
+}
+
+// columns returns the index of the time column and the indices of all value columns of the given table.
+func (i *InfluxDB) columns(table influxDBTable) (int, []int) {
+	if i.language == influxDBLanguageFlux {
+		valueColumn := columnIndex(table.Columns, "_value")
+		if valueColumn == -1 {
+			return columnIndex(table.Columns, "_time"), nil
+		}
+		return columnIndex(table.Columns, "_time"), []int{valueColumn}
+	}
+
+	var valueColumns []int
+	for index, column := range table.Columns {
+		if column != "time" {
+			valueColumns = append(valueColumns, index)
+		}
+	}
+
+	return columnIndex(table.Columns, "time"), valueColumns
+}
+
+// labels returns the labels for a series. The labels are the tags of the series, the measurement (_measurement) and
+// the field (_field), so that the same label templates can be used for InfluxQL and Flux queries.
+func (i *InfluxDB) labels(table influxDBTable, valueColumn int) map[string]string {
+	var labels map[string]string
+	labels = make(map[string]string)
+
+	for key, value := range table.Tags {
+		labels[key] = value
+	}
+
+	if i.language == influxDBLanguageInfluxQL {
+		labels["_measurement"] = table.Name
+		labels["_field"] = table.Columns[valueColumn]
+	}
+
+	return labels
+}
+
+func (i *InfluxDB) query(ctx context.Context, query string) ([]influxDBTable, error) {
+	fLog.Debugf("run %s query: %s", i.language, query)
+
+	if i.language == influxDBLanguageFlux {
+		return i.queryFlux(ctx, query)
+	}
+
+	return i.queryInfluxQL(ctx, query)
+}
+
+func (i *InfluxDB) queryInfluxQL(ctx context.Context, query string) ([]influxDBTable, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("epoch", "ms")
+	if i.database != "" {
+		params.Set("db", i.database)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, i.url+"/query?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := i.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var res influxDBResponse
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	err = decoder.Decode(&res)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.Error != "" {
+		return nil, fmt.Errorf("query failed: %s", res.Error)
+	}
+
+	var tables []influxDBTable
+
+	for _, result := range res.Results {
+		if result.Error != "" {
+			return nil, fmt.Errorf("query failed: %s", result.Error)
+		}
+
+		for _, series := range result.Series {
+			tables = append(tables, influxDBTable{
+				Name:    series.Name,
+				Tags:    series.Tags,
+				Columns: series.Columns,
+				Rows:    series.Values,
+			})
+		}
+	}
+
+	return tables, nil
+}
+
+func (i *InfluxDB) queryFlux(ctx context.Context, query string) ([]influxDBTable, error) {
+	reqBody, err := json.Marshal(map[string]interface{}{
+		"query": query,
+		"type":  "flux",
+		"dialect": map[string]interface{}{
+			"header":      true,
+			"annotations": []string{},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	if i.organization != "" {
+		params.Set("org", i.organization)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, i.url+"/api/v2/query?"+params.Encode(), bytes.NewReader(reqBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+
+	body, err := i.do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The result of a Flux query is a CSV file, where each table starts with a header row. The "table" column contains
+	// the id of the table, which is used to group the rows into series. All columns except the reserved ones are used as
+	// tags for the series.
+	reader := csv.NewReader(bytes.NewReader(body))
+	reader.FieldsPerRecord = -1
+
+	var tables []influxDBTable
+	var header []string
+	tableIndex := make(map[string]int)
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) < 3 || strings.HasPrefix(record[0], "#") {
+			continue
+		}
+
+		if record[1] == "result" && record[2] == "table" {
+			header = record
+			tableIndex = make(map[string]int)
+			continue
+		}
+
+		if header == nil || len(record) != len(header) {
+			continue
+		}
+
+		index, ok := tableIndex[record[2]]
+		if !ok {
+			tags := make(map[string]string)
+			for column, name := range header {
+				switch name {
+				case "", "result", "table", "_start", "_stop", "_time", "_value":
+				default:
+					tags[name] = record[column]
+				}
+			}
+
+			tables = append(tables, influxDBTable{
+				Tags:    tags,
+				Columns: header,
+			})
+			index = len(tables) - 1
+			tableIndex[record[2]] = index
+		}
+
+		row := make([]interface{}, len(record))
+		for column, value := range record {
+			row[column] = value
+		}
+
+		tables[index].Rows = append(tables[index].Rows, row)
+	}
+
+	return tables, nil
+}
+
+func (i *InfluxDB) do(ctx context.Context, req *http.Request) ([]byte, error) {
+	if i.token != "" {
+		req.Header.Set("Authorization", "Token "+i.token)
+	}
+
+	resp, err := i.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errorResponse influxDBErrorResponse
+		if err := json.Unmarshal(body, &errorResponse); err == nil && errorResponse.Message != "" {
+			return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, errorResponse.Message)
+		}
+
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return body, nil
+}
+
+func parseInfluxDBTime(value interface{}) (time.Time, error) {
+	switch v := value.(type) {
+	case json.Number:
+		msec, err := v.Int64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Unix(0, msec*int64(time.Millisecond)), nil
+	case string:
+		return time.Parse(time.RFC3339Nano, v)
+	default:
+		return time.Time{}, fmt.Errorf("invalid timestamp: %v", value)
+	}
+}
+
+func parseInfluxDBValue(value interface{}) float64 {
+	switch v := value.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return math.NaN()
+		}
+		return f
+	case bool:
+		if v {
+			return 1
+		}
+		return 0
+	default:
+		return math.NaN()
+	}
+}
+
+func columnIndex(columns []string, name string) int {
+	for index, column := range columns {
+		if column == name {
+			return index
+		}
+	}
+
+	return -1
+}
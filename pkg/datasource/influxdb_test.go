@@ -0,0 +1,155 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInfluxDBGetDataInfluxQL(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577836860, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/query" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		if db := r.URL.Query().Get("db"); db != "telegraf" {
+			t.Errorf("unexpected database %s", db)
+		}
+		if auth := r.Header.Get("Authorization"); auth != "Token secret" {
+			t.Errorf("unexpected authorization header %s", auth)
+		}
+
+		expectedQuery := "SELECT mean(usage_idle) FROM cpu WHERE time >= 1577836800000ms and time <= 1577836860000ms GROUP BY time(30s), host"
+		if query := r.URL.Query().Get("q"); query != expectedQuery {
+			t.Errorf("unexpected query %s", query)
+		}
+
+		fmt.Fprint(w, `{"results":[{"series":[
+			{"name":"cpu","tags":{"host":"server-1"},"columns":["time","mean"],"values":[[1577836800000,10.5],[1577836830000,null]]},
+			{"name":"cpu","tags":{"host":"server-2"},"columns":["time","mean"],"values":[[1577836800000,20],[1577836830000,30]]}
+		]}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewInfluxDBClient(Datasource{URL: server.URL, Database: "telegraf", Auth: Auth{Token: "secret"}, Options: Options{Step: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := client.GetData(context.Background(), []string{"SELECT mean(usage_idle) FROM cpu WHERE $__timeFilter GROUP BY time($__interval), host"}, []string{"{{.host}}"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Times) != 2 || !data.Times[0].Equal(start) || !data.Times[1].Equal(start.Add(30*time.Second)) {
+		t.Fatalf("unexpected times %v", data.Times)
+	}
+
+	if len(data.Series) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(data.Series))
+	}
+
+	if data.Series[0].Label != "server-1" || data.Series[1].Label != "server-2" {
+		t.Errorf("unexpected labels %s and %s", data.Series[0].Label, data.Series[1].Label)
+	}
+
+	if data.Series[0].Labels["_measurement"] != "cpu" || data.Series[0].Labels["_field"] != "mean" {
+		t.Errorf("unexpected labels %v", data.Series[0].Labels)
+	}
+
+	if data.Series[0].Points[0] != 10.5 || !math.IsNaN(data.Series[0].Points[1]) {
+		t.Errorf("unexpected points %v", data.Series[0].Points)
+	}
+}
+
+func TestInfluxDBGetDataFlux(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/query" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		if org := r.URL.Query().Get("org"); org != "my-org" {
+			t.Errorf("unexpected organization %s", org)
+		}
+
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !strings.Contains(string(body), `range(start: 2020-01-01T00:00:00Z, stop: 2020-01-01T00:01:00Z)`) {
+			t.Errorf("macros were not replaced: %s", body)
+		}
+
+		fmt.Fprint(w, ",result,table,_start,_stop,_time,_value,_field,host\r\n"+
+			",_result,0,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:00Z,1,usage_idle,server-1\r\n"+
+			",_result,0,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:30Z,2,usage_idle,server-1\r\n"+
+			",_result,1,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:00Z,3,usage_idle,server-2\r\n"+
+			",_result,1,2020-01-01T00:00:00Z,2020-01-01T00:01:00Z,2020-01-01T00:00:30Z,4,usage_idle,server-2\r\n")
+	}))
+	defer server.Close()
+
+	client, err := NewInfluxDBClient(Datasource{URL: server.URL, Organization: "my-org", Language: "flux"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	data, err := client.GetData(context.Background(), []string{`from(bucket: "telegraf") |> range(start: $__timeFrom, stop: $__timeTo)`}, []string{"{{.host}} {{._field}}"}, start, start.Add(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Times) != 2 || len(data.Series) != 2 {
+		t.Fatalf("unexpected data %v", data)
+	}
+
+	if data.Series[1].Label != "server-2 usage_idle" || data.Series[1].Points[1] != 4 {
+		t.Errorf("unexpected series %v", data.Series[1])
+	}
+}
+
+func TestInfluxDBBasicAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "admin" || password != "password" {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"authorization failed"}`)
+			return
+		}
+
+		fmt.Fprint(w, `{"results":[{"series":[{"name":"measurements","columns":["name"],"values":[["cpu"],["mem"]]}]}]}`)
+	}))
+	defer server.Close()
+
+	client, err := NewInfluxDBClient(Datasource{URL: server.URL, Auth: Auth{Username: "admin", Password: "password"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	suggestions, err := client.GetSuggestions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Join(suggestions, ",") != "cpu,mem" {
+		t.Errorf("unexpected suggestions %v", suggestions)
+	}
+
+	client, err = NewInfluxDBClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.GetSuggestions(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected an error for an unauthorized request, got %v", err)
+	}
+}
@@ -0,0 +1,333 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+// JSON implements the Client interface for services, which are exposing their metrics as plain JSON. A query consists
+// of multiple parts, which are separated by "|". The first part is always the path, which is appended to the url of
+// the datasource. The other parts are JSONPath expressions to extract the data from the returned JSON document:
+//
+//	Graphs:    <path> | <timestamps> | <values> | <labels>
+//	Tables:    <path> | <rows>
+//	Variables: <path> | <values>
+//
+// For graphs the labels expression is optional. If it is provided the points are grouped into series by the returned
+// labels. A label can be a string, which can be used via "{{.label}}" in the label template or an object, where each
+// key can be used in the label template. For tables each row must be an object, where each key is a column.
+//
+// The path can contain the variables of the dashboard and the start and end time of the selected interval as unix
+// timestamps, e.g. "/api/metrics?from={{.start}}&to={{.end}}".
+type JSON struct {
+	client  *http.Client
+	url     string
+	options Options
+}
+
+func NewJSONClient(datasource Datasource) (*JSON, error) {
+	_, err := url.Parse(datasource.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &JSON{
+		client:  &http.Client{Transport: newRoundTripper(datasource.Auth)},
+		url:     strings.TrimSuffix(datasource.URL, "/"),
+		options: datasource.Options,
+	}, nil
+}
+
+func (j *JSON) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(j.options))
+	defer cancel()
+
+	parts, err := splitJSONQuery(query, 2, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	document, err := j.get(ctx, parts[0])
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := jsonPath(document, parts[1])
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, result := range results {
+		if object, ok := result.(map[string]interface{}); ok {
+			if value, ok := object[label]; ok && value != nil {
+				values = appendIfMissing(values, formatJSONValue(value))
+			}
+			continue
+		}
+
+		if result != nil {
+			values = appendIfMissing(values, formatJSONValue(result))
+		}
+	}
+
+	return values, nil
+}
+
+func (j *JSON) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(j.options))
+	defer cancel()
+
+	var series []Series
+	var times []time.Time
+
+	for i, query := range queries {
+		parts, err := splitJSONQuery(query, 3, 4)
+		if err != nil {
+			return nil, err
+		}
+
+		document, err := j.get(ctx, parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		returnedTimes, err := jsonPath(document, parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		values, err := jsonPath(document, parts[2])
+		if err != nil {
+			return nil, err
+		}
+
+		if len(returnedTimes) != len(values) {
+			return nil, fmt.Errorf("query %s returned %d timestamps and %d values", query, len(returnedTimes), len(values))
+		}
+
+		var returnedLabels []interface{}
+		if len(parts) == 4 {
+			returnedLabels, err = jsonPath(document, parts[3])
+			if err != nil {
+				return nil, err
+			}
+
+			if len(returnedLabels) != len(values) {
+				return nil, fmt.Errorf("query %s returned %d labels and %d values", query, len(returnedLabels), len(values))
+			}
+		}
+
+		// Each point is converted into a sample, where the label is the JSON encoded label returned for the point. The
+		// label is encoded so that labels which are objects can be used to group the points and are decoded again to
+		// render the label template.
+		var samples []sample
+
+		for index := range values {
+			timestamp, err := parseTime(returnedTimes[index])
+			if err != nil {
+				return nil, err
+			}
+
+			var label string
+			if returnedLabels != nil {
+				encoded, err := json.Marshal(returnedLabels[index])
+				if err != nil {
+					return nil, err
+				}
+				label = string(encoded)
+			}
+
+			samples = append(samples, sample{
+				Label:     label,
+				Timestamp: timestamp,
+				Value:     parseValue(values[index]),
+			})
+		}
+
+		alignedTimes, alignedLabels, points := alignSamples(samples)
+
+		for index, label := range alignedLabels {
+			seriesLabels := jsonLabels(label)
+			fLog.Debugf("query %s returned %d points and the following labels %v", query, len(points[index]), seriesLabels)
+
+			if len(series) == 0 {
+				times = append(times, alignedTimes...)
+			}
+
+			series = append(series, Series{
+				Label:  getLabel(labels[i], seriesLabels),
+				Labels: seriesLabels,
+				Points: points[index],
+			})
+		}
+	}
+
+	return &Data{
+		Times:  times,
+		Series: series,
+	}, nil
+}
+
+func (j *JSON) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(j.options))
+	defer cancel()
+
+	var tableData TableData
+	tableData = make(map[string]map[string]interface{})
+
+	for i, query := range queries {
+		parts, err := splitJSONQuery(query, 2, 2)
+		if err != nil {
+			return nil, err
+		}
+
+		document, err := j.get(ctx, parts[0])
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err := jsonPath(document, parts[1])
+		if err != nil {
+			return nil, err
+		}
+
+		for _, row := range rows {
+			object, ok := row.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("query %s returned a row which is not an object: %v", query, row)
+			}
+
+			var returnedLabels map[string]string
+			returnedLabels = make(map[string]string)
+
+			for key, value := range object {
+				if value != nil {
+					returnedLabels[key] = formatJSONValue(value)
+				}
+			}
+
+			joinValue := getLabel(labels[i], returnedLabels)
+			if _, ok := tableData[joinValue]; !ok {
+				tableData[joinValue] = make(map[string]interface{})
+			}
+
+			for key, value := range object {
+				if _, ok := tableData[joinValue][key]; !ok {
+					tableData[joinValue][key] = value
+				}
+
+				if key == "value" {
+					tableData[joinValue][fmt.Sprintf("value_%d", i)] = parseValue(value)
+				}
+			}
+		}
+	}
+
+	return &tableData, nil
+}
+
+// GetSuggestions returns no suggestions, because there is no generic way to get the available metrics of a JSON API.
+func (j *JSON) GetSuggestions(ctx context.Context) ([]string, error) {
+	return nil, nil
+}
+
+func (j *JSON) get(ctx context.Context, path string) (interface{}, error) {
+	fLog.Debugf("get json document %s", path)
+
+	req, err := http.NewRequest(http.MethodGet, j.url+"/"+strings.TrimPrefix(path, "/"), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := j.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var document interface{}
+	err = json.Unmarshal(body, &document)
+	if err != nil {
+		return nil, err
+	}
+
+	return document, nil
+}
+
+// splitJSONQuery splits the query into the path and the JSONPath expressions. It returns an error if the number of
+// parts is not between min and max.
+func splitJSONQuery(query string, min, max int) ([]string, error) {
+	parts := strings.Split(query, "|")
+	if len(parts) < min || len(parts) > max {
+		return nil, fmt.Errorf("invalid query %s: expected between %d and %d parts, got %d", query, min, max, len(parts))
+	}
+
+	for index := range parts {
+		parts[index] = strings.TrimSpace(parts[index])
+	}
+
+	return parts, nil
+}
+
+// jsonLabels decodes the JSON encoded label of a series. If the label is an object each key is returned as label,
+// otherwise the value is returned as "label".
+func jsonLabels(encoded string) map[string]string {
+	var labels map[string]string
+	labels = make(map[string]string)
+
+	if encoded == "" {
+		return labels
+	}
+
+	var value interface{}
+	if err := json.Unmarshal([]byte(encoded), &value); err != nil {
+		return labels
+	}
+
+	if object, ok := value.(map[string]interface{}); ok {
+		for key, v := range object {
+			if v != nil {
+				labels[key] = formatJSONValue(v)
+			}
+		}
+		return labels
+	}
+
+	labels["label"] = formatJSONValue(value)
+	return labels
+}
+
+func formatJSONValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
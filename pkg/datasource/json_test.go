@@ -0,0 +1,173 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func newJSONTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Get("Accept"); accept != "application/json" {
+			t.Errorf("unexpected accept header %s", accept)
+		}
+
+		switch r.URL.Path {
+		case "/api/metrics":
+			if from := r.URL.Query().Get("from"); from != "1577836800" {
+				t.Errorf("unexpected from parameter %s", from)
+			}
+
+			fmt.Fprint(w, `{"data": [
+				{"time": 1577836800, "value": 1, "labels": {"host": "server-1", "region": "eu"}},
+				{"time": 1577836800, "value": 2, "labels": {"host": "server-2", "region": "us"}},
+				{"time": 1577836860, "value": "3", "labels": {"host": "server-1", "region": "eu"}}
+			]}`)
+		case "/api/status":
+			fmt.Fprint(w, `{"data": [
+				{"time": "2020-01-01T00:00:00Z", "value": 200, "code": "ok"},
+				{"time": "2020-01-01T00:01:00Z", "value": 500, "code": "error"}
+			]}`)
+		case "/api/services":
+			fmt.Fprint(w, `{"services": [
+				{"name": "api", "replicas": 3, "value": 0.5, "ready": true},
+				{"name": "web", "replicas": 2, "value": 1, "ready": null}
+			]}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, "not found")
+		}
+	}))
+}
+
+func TestJSONGetData(t *testing.T) {
+	server := newJSONTestServer(t)
+	defer server.Close()
+
+	client, err := NewJSONClient(Datasource{URL: server.URL + "/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577836860, 0)
+
+	t.Run("object labels", func(t *testing.T) {
+		data, err := client.GetData(context.Background(), []string{"/api/metrics?from=1577836800 | $.data[*].time | $.data[*].value | $.data[*].labels"}, []string{"{{.host}} ({{.region}})"}, start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(data.Times) != 2 || !data.Times[0].Equal(start) || !data.Times[1].Equal(end) {
+			t.Fatalf("unexpected times %v", data.Times)
+		}
+
+		if len(data.Series) != 2 {
+			t.Fatalf("expected 2 series, got %d", len(data.Series))
+		}
+
+		if data.Series[0].Label != "server-1 (eu)" || data.Series[0].Labels["region"] != "eu" {
+			t.Errorf("unexpected series %v", data.Series[0])
+		}
+
+		if data.Series[0].Points[0] != 1 || data.Series[0].Points[1] != 3 {
+			t.Errorf("unexpected points %v", data.Series[0].Points)
+		}
+
+		// The second series has no value for the second timestamp, so that the point must be filled with NaN.
+		if data.Series[1].Label != "server-2 (us)" || data.Series[1].Points[0] != 2 || !math.IsNaN(data.Series[1].Points[1]) {
+			t.Errorf("unexpected series %v", data.Series[1])
+		}
+	})
+
+	t.Run("scalar labels", func(t *testing.T) {
+		data, err := client.GetData(context.Background(), []string{"api/status | $.data[*].time | $.data[*].value | $.data[*].code"}, []string{"status {{.label}}"}, start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(data.Series) != 2 || data.Series[0].Label != "status ok" || data.Series[1].Label != "status error" {
+			t.Fatalf("unexpected series %v", data.Series)
+		}
+
+		if data.Series[0].Points[0] != 200 || !math.IsNaN(data.Series[0].Points[1]) {
+			t.Errorf("unexpected points %v", data.Series[0].Points)
+		}
+
+		if !math.IsNaN(data.Series[1].Points[0]) || data.Series[1].Points[1] != 500 {
+			t.Errorf("unexpected points %v", data.Series[1].Points)
+		}
+	})
+
+	t.Run("without labels", func(t *testing.T) {
+		data, err := client.GetData(context.Background(), []string{"/api/status | $.data[*].time | $.data[*].value"}, []string{"status"}, start, end)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(data.Series) != 1 || data.Series[0].Label != "status" || len(data.Series[0].Labels) != 0 {
+			t.Fatalf("unexpected series %v", data.Series)
+		}
+
+		if data.Series[0].Points[0] != 200 || data.Series[0].Points[1] != 500 {
+			t.Errorf("unexpected points %v", data.Series[0].Points)
+		}
+	})
+
+	for _, tc := range []struct {
+		name  string
+		query string
+	}{
+		{name: "invalid query", query: "/api/status | $.data[*].time"},
+		{name: "invalid json path", query: "/api/status | data | $.data[*].value"},
+		{name: "mismatched values", query: "/api/status | $.data[*].time | $.data[0].value"},
+		{name: "mismatched labels", query: "/api/status | $.data[*].time | $.data[*].value | $.data[0].code"},
+		{name: "invalid timestamp", query: "/api/status | $.data[*].code | $.data[*].value"},
+		{name: "not found", query: "/api/missing | $.data[*].time | $.data[*].value"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := client.GetData(context.Background(), []string{tc.query}, []string{""}, start, end); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestJSONGetTableData(t *testing.T) {
+	server := newJSONTestServer(t)
+	defer server.Close()
+
+	client, err := NewJSONClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tableData, err := client.GetTableData(context.Background(), []string{"/api/services | $.services[*]"}, []string{"{{.name}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*tableData) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(*tableData))
+	}
+
+	api := (*tableData)["api"]
+	if api["replicas"] != 3.0 || api["ready"] != true || api["value_0"] != 0.5 {
+		t.Errorf("unexpected row %v", api)
+	}
+
+	web := (*tableData)["web"]
+	if web["replicas"] != 2.0 || web["ready"] != nil || web["value_0"] != 1.0 {
+		t.Errorf("unexpected row %v", web)
+	}
+
+	if _, err := client.GetTableData(context.Background(), []string{"/api/services | $.services[*].name"}, []string{""}); err == nil {
+		t.Error("expected an error for rows which are not objects")
+	}
+}
@@ -0,0 +1,192 @@
+package datasource
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type jsonPathStep struct {
+	key       string
+	index     int
+	wildcard  bool
+	recursive bool
+	isIndex   bool
+}
+
+// jsonPath evaluates the given JSONPath expression against the decoded JSON value and returns all matching values. The
+// following subset of JSONPath is supported:
+//
+//	$              the root element
+//	.key, ['key']  a child element
+//	[n]            an array element, negative indices are counted from the end of the array
+//	[*], .*        all children of an object or array
+//	..key          a recursive descent to all elements with the given key
+func jsonPath(value interface{}, expression string) ([]interface{}, error) {
+	steps, err := parseJSONPath(expression)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := []interface{}{value}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			if step.recursive {
+				next = append(next, jsonPathDescendants(node, step)...)
+			} else {
+				next = append(next, jsonPathChildren(node, step)...)
+			}
+		}
+		nodes = next
+	}
+
+	return nodes, nil
+}
+
+func parseJSONPath(expression string) ([]jsonPathStep, error) {
+	expression = strings.TrimSpace(expression)
+	if !strings.HasPrefix(expression, "$") {
+		return nil, fmt.Errorf("invalid json path %s: must start with $", expression)
+	}
+
+	var steps []jsonPathStep
+	rest := expression[1:]
+
+	for len(rest) > 0 {
+		var step jsonPathStep
+		var err error
+
+		recursive := strings.HasPrefix(rest, "..")
+
+		switch {
+		case recursive && strings.HasPrefix(rest[2:], "["):
+			step, rest, err = parseJSONPathSelector(rest[2:])
+		case recursive:
+			step, rest, err = parseJSONPathKey(rest[2:])
+		case strings.HasPrefix(rest, "."):
+			step, rest, err = parseJSONPathKey(rest[1:])
+		case strings.HasPrefix(rest, "["):
+			step, rest, err = parseJSONPathSelector(rest)
+		default:
+			err = fmt.Errorf("unexpected %s", rest)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid json path %s: %s", expression, err.Error())
+		}
+
+		step.recursive = recursive
+		steps = append(steps, step)
+	}
+
+	return steps, nil
+}
+
+// parseJSONPathKey parses a key in the dot notation, e.g. "key" or "*".
+func parseJSONPathKey(rest string) (jsonPathStep, string, error) {
+	end := strings.IndexAny(rest, ".[")
+	if end == -1 {
+		end = len(rest)
+	}
+
+	key := rest[:end]
+	if key == "" {
+		return jsonPathStep{}, rest, fmt.Errorf("empty key")
+	}
+
+	if key == "*" {
+		return jsonPathStep{wildcard: true}, rest[end:], nil
+	}
+
+	return jsonPathStep{key: key}, rest[end:], nil
+}
+
+// parseJSONPathSelector parses a selector in the bracket notation, e.g. "['key']", "[0]" or "[*]".
+func parseJSONPathSelector(rest string) (jsonPathStep, string, error) {
+	end := strings.Index(rest, "]")
+	if end == -1 {
+		return jsonPathStep{}, rest, fmt.Errorf("missing ]")
+	}
+
+	selector := strings.TrimSpace(rest[1:end])
+	rest = rest[end+1:]
+
+	if selector == "*" {
+		return jsonPathStep{wildcard: true}, rest, nil
+	}
+
+	if strings.HasPrefix(selector, "'") || strings.HasPrefix(selector, "\"") {
+		return jsonPathStep{key: strings.Trim(selector, "'\"")}, rest, nil
+	}
+
+	index, err := strconv.Atoi(selector)
+	if err != nil {
+		return jsonPathStep{}, rest, fmt.Errorf("invalid index %s", selector)
+	}
+
+	return jsonPathStep{index: index, isIndex: true}, rest, nil
+}
+
+func jsonPathChildren(node interface{}, step jsonPathStep) []interface{} {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if step.wildcard {
+			keys := make([]string, 0, len(n))
+			for key := range n {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			var children []interface{}
+			for _, key := range keys {
+				children = append(children, n[key])
+			}
+			return children
+		}
+
+		if value, ok := n[step.key]; ok && !step.isIndex {
+			return []interface{}{value}
+		}
+	case []interface{}:
+		if step.wildcard {
+			return n
+		}
+
+		if step.isIndex {
+			index := step.index
+			if index < 0 {
+				index = len(n) + index
+			}
+			if index >= 0 && index < len(n) {
+				return []interface{}{n[index]}
+			}
+		}
+	}
+
+	return nil
+}
+
+func jsonPathDescendants(node interface{}, step jsonPathStep) []interface{} {
+	result := jsonPathChildren(node, step)
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			result = append(result, jsonPathDescendants(n[key], step)...)
+		}
+	case []interface{}:
+		for _, child := range n {
+			result = append(result, jsonPathDescendants(child, step)...)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,99 @@
+package datasource
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPath(t *testing.T) {
+	for _, tc := range []struct {
+		expression string
+		expected   []jsonPathStep
+		err        bool
+	}{
+		{expression: "$", expected: nil},
+		{expression: " $.data ", expected: []jsonPathStep{{key: "data"}}},
+		{expression: "$.data.result", expected: []jsonPathStep{{key: "data"}, {key: "result"}}},
+		{expression: "$['data'][\"result\"]", expected: []jsonPathStep{{key: "data"}, {key: "result"}}},
+		{expression: "$.data[0]", expected: []jsonPathStep{{key: "data"}, {index: 0, isIndex: true}}},
+		{expression: "$.data[-1]", expected: []jsonPathStep{{key: "data"}, {index: -1, isIndex: true}}},
+		{expression: "$.data[*].value", expected: []jsonPathStep{{key: "data"}, {wildcard: true}, {key: "value"}}},
+		{expression: "$.data.*", expected: []jsonPathStep{{key: "data"}, {wildcard: true}}},
+		{expression: "$..value", expected: []jsonPathStep{{key: "value", recursive: true}}},
+		{expression: "$..[0]", expected: []jsonPathStep{{index: 0, isIndex: true, recursive: true}}},
+		{expression: "data", err: true},
+		{expression: "$data", err: true},
+		{expression: "$.", err: true},
+		{expression: "$.data.", err: true},
+		{expression: "$[0", err: true},
+		{expression: "$[abc]", err: true},
+	} {
+		t.Run(tc.expression, func(t *testing.T) {
+			steps, err := parseJSONPath(tc.expression)
+			if tc.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %v", steps)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(steps, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, steps)
+			}
+		})
+	}
+}
+
+func TestJSONPath(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(`{
+		"status": "ok",
+		"data": [
+			{"time": 1, "value": 10, "labels": {"host": "a"}},
+			{"time": 2, "value": 20, "labels": {"host": "b"}}
+		],
+		"meta": {"b": 2, "a": 1}
+	}`), &document); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		expression string
+		expected   []interface{}
+	}{
+		{expression: "$.status", expected: []interface{}{"ok"}},
+		{expression: "$['status']", expected: []interface{}{"ok"}},
+		{expression: "$.data[*].value", expected: []interface{}{10.0, 20.0}},
+		{expression: "$.data.*.time", expected: []interface{}{1.0, 2.0}},
+		{expression: "$.data[0].labels.host", expected: []interface{}{"a"}},
+		{expression: "$.data[-1].labels.host", expected: []interface{}{"b"}},
+		{expression: "$.meta.*", expected: []interface{}{1.0, 2.0}},
+		{expression: "$..host", expected: []interface{}{"a", "b"}},
+		{expression: "$.missing", expected: nil},
+		{expression: "$.data[2]", expected: nil},
+		{expression: "$.data[-3]", expected: nil},
+		{expression: "$.data.value", expected: nil},
+		{expression: "$.status[0]", expected: nil},
+		{expression: "$.meta[0]", expected: nil},
+	} {
+		t.Run(tc.expression, func(t *testing.T) {
+			values, err := jsonPath(document, tc.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(values, tc.expected) {
+				t.Errorf("expected %v, got %v", tc.expected, values)
+			}
+		})
+	}
+
+	if _, err := jsonPath(document, "status"); err == nil {
+		t.Error("expected an error for an invalid expression")
+	}
+}
@@ -0,0 +1,328 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+type Loki struct {
+	client  *http.Client
+	url     string
+	options Options
+}
+
+type lokiResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		ResultType string          `json:"resultType"`
+		Result     json.RawMessage `json:"result"`
+	} `json:"data"`
+}
+
+type lokiLabelsResponse struct {
+	Status string   `json:"status"`
+	Error  string   `json:"error"`
+	Data   []string `json:"data"`
+}
+
+type lokiMatrix []struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+type lokiVector []struct {
+	Metric map[string]string `json:"metric"`
+	Value  [2]interface{}    `json:"value"`
+}
+
+type lokiStreams []struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+func NewLokiClient(datasource Datasource) (*Loki, error) {
+	_, err := url.Parse(datasource.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Loki{
+		client:  &http.Client{Transport: newRoundTripper(datasource.Auth)},
+		url:     strings.TrimSuffix(datasource.URL, "/"),
+		options: datasource.Options,
+	}, nil
+}
+
+func (l *Loki) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(l.options))
+	defer cancel()
+
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+	if query != "" {
+		params.Set("query", query)
+	}
+
+	var res lokiLabelsResponse
+	err := l.get(ctx, "/loki/api/v1/label/"+url.PathEscape(label)+"/values", params, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	var values []string
+	for _, value := range res.Data {
+		values = appendIfMissing(values, value)
+	}
+
+	return values, nil
+}
+
+func (l *Loki) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(l.options))
+	defer cancel()
+
+	var series []Series
+	var times []time.Time
+
+	timeRange := getTimeRange(ctx, l.options, start, end)
+	traceAligned(ctx, timeRange.Step)
+
+	for i, query := range queries {
+		params := url.Values{}
+		params.Set("query", query)
+		params.Set("start", strconv.FormatInt(timeRange.Start.UnixNano(), 10))
+		params.Set("end", strconv.FormatInt(timeRange.End.UnixNano(), 10))
+		params.Set("step", strconv.FormatFloat(timeRange.Step.Seconds(), 'f', -1, 64))
+
+		var res lokiResponse
+		err := l.get(ctx, "/loki/api/v1/query_range", params, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Data.ResultType != "matrix" {
+			return nil, fmt.Errorf("unsupported result format: %s", res.Data.ResultType)
+		}
+
+		var data lokiMatrix
+		err = json.Unmarshal(res.Data.Result, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, d := range data {
+			fLog.Debugf("query %s returned %d points and the following labels %v", query, len(d.Values), d.Metric)
+
+			var points []float64
+
+			for _, value := range d.Values {
+				timestamp, point, err := parseLokiSample(value)
+				if err != nil {
+					return nil, err
+				}
+
+				if i == 0 && j == 0 {
+					times = append(times, timestamp)
+				}
+				points = append(points, point)
+			}
+
+			series = append(series, Series{
+				Label:  getLabel(labels[i], d.Metric),
+				Labels: d.Metric,
+				Points: points,
+			})
+		}
+	}
+
+	return &Data{
+		Times:  times,
+		Series: series,
+	}, nil
+}
+
+func (l *Loki) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(l.options))
+	defer cancel()
+
+	var tableData TableData
+	tableData = make(map[string]map[string]interface{})
+
+	now := time.Now()
+
+	for i, query := range queries {
+		params := url.Values{}
+		params.Set("query", query)
+		params.Set("time", strconv.FormatInt(now.UnixNano(), 10))
+
+		var res lokiResponse
+		err := l.get(ctx, "/loki/api/v1/query", params, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Data.ResultType != "vector" {
+			return nil, fmt.Errorf("unsupported result format: %s", res.Data.ResultType)
+		}
+
+		var data lokiVector
+		err = json.Unmarshal(res.Data.Result, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range data {
+			_, point, err := parseLokiSample(d.Value)
+			if err != nil {
+				return nil, err
+			}
+
+			joinValue := getLabel(labels[i], d.Metric)
+			for key, value := range d.Metric {
+				if _, ok := tableData[joinValue]; !ok {
+					tableData[joinValue] = make(map[string]interface{})
+				}
+
+				if _, ok := tableData[joinValue][key]; !ok {
+					tableData[joinValue][key] = value
+				}
+
+				tableData[joinValue][fmt.Sprintf("value_%d", i)] = point
+			}
+		}
+	}
+
+	return &tableData, nil
+}
+
+func (l *Loki) GetSuggestions(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(l.options))
+	defer cancel()
+
+	var res lokiLabelsResponse
+	err := l.get(ctx, "/loki/api/v1/labels", url.Values{}, &res)
+	if err != nil {
+		return nil, err
+	}
+
+	return res.Data, nil
+}
+
+func (l *Loki) GetLogs(ctx context.Context, queries, labels []string, start, end time.Time, limit int) ([]LogLine, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(l.options))
+	defer cancel()
+
+	var lines []LogLine
+
+	for i, query := range queries {
+		params := url.Values{}
+		params.Set("query", query)
+		params.Set("start", strconv.FormatInt(start.UnixNano(), 10))
+		params.Set("end", strconv.FormatInt(end.UnixNano(), 10))
+		params.Set("limit", strconv.Itoa(limit))
+		params.Set("direction", "backward")
+
+		var res lokiResponse
+		err := l.get(ctx, "/loki/api/v1/query_range", params, &res)
+		if err != nil {
+			return nil, err
+		}
+
+		if res.Data.ResultType != "streams" {
+			return nil, fmt.Errorf("unsupported result format: %s", res.Data.ResultType)
+		}
+
+		var data lokiStreams
+		err = json.Unmarshal(res.Data.Result, &data)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range data {
+			fLog.Debugf("query %s returned %d log lines and the following labels %v", query, len(d.Values), d.Stream)
+
+			label := getLabel(labels[i], d.Stream)
+
+			for _, value := range d.Values {
+				nsec, err := strconv.ParseInt(value[0], 10, 64)
+				if err != nil {
+					return nil, err
+				}
+
+				lines = append(lines, LogLine{
+					Timestamp: time.Unix(0, nsec),
+					Label:     label,
+					Line:      value[1],
+				})
+			}
+		}
+	}
+
+	// The log lines of all streams are sorted by their timestamp, so that the newest log lines are at the end of the
+	// returned slice. When the number of log lines exceeds the limit, we only keep the newest ones.
+	sort.SliceStable(lines, func(i, j int) bool {
+		return lines[i].Timestamp.Before(lines[j].Timestamp)
+	})
+
+	if limit > 0 && len(lines) > limit {
+		lines = lines[len(lines)-limit:]
+	}
+
+	return lines, nil
+}
+
+func (l *Loki) get(ctx context.Context, path string, params url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, l.url+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := l.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+func parseLokiSample(sample [2]interface{}) (time.Time, float64, error) {
+	timestamp, ok := sample[0].(float64)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid timestamp: %v", sample[0])
+	}
+
+	value, ok := sample[1].(string)
+	if !ok {
+		return time.Time{}, 0, fmt.Errorf("invalid value: %v", sample[1])
+	}
+
+	point, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+
+	sec := int64(timestamp)
+	return time.Unix(sec, int64((timestamp-float64(sec))*1e9)), point, nil
+}
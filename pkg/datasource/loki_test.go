@@ -0,0 +1,184 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLokiGetData(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577836860, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/query_range" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		query := r.URL.Query()
+		if query.Get("query") != `sum(rate({app="api"}[1m])) by (level)` || query.Get("start") != "1577836800000000000" || query.Get("end") != "1577836860000000000" || query.Get("step") != "30" {
+			t.Errorf("unexpected parameters %v", query)
+		}
+
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"matrix","result":[
+			{"metric":{"level":"info"},"values":[[1577836800,"10"],[1577836830,"12.5"],[1577836860,"8"]]},
+			{"metric":{"level":"error"},"values":[[1577836800,"1"],[1577836830,"0"],[1577836860,"3"]]}
+		]}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewLokiClient(Datasource{URL: server.URL + "/", Options: Options{Step: 30}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := &Trace{}
+	data, err := client.GetData(WithTrace(context.Background(), trace), []string{`sum(rate({app="api"}[1m])) by (level)`}, []string{"{{.level}}"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if trace.Step != 30*time.Second || !trace.Aligned {
+		t.Errorf("unexpected trace %v", trace)
+	}
+
+	if len(data.Times) != 3 || !data.Times[0].Equal(start) || !data.Times[2].Equal(end) {
+		t.Fatalf("unexpected times %v", data.Times)
+	}
+
+	if len(data.Series) != 2 || data.Series[0].Label != "info" || data.Series[1].Label != "error" {
+		t.Fatalf("unexpected series %v", data.Series)
+	}
+
+	expected := []float64{10, 12.5, 8}
+	for index, point := range data.Series[0].Points {
+		if point != expected[index] {
+			t.Errorf("expected point %v at index %d, got %v", expected[index], index, point)
+		}
+	}
+}
+
+func TestLokiGetDataErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "status code", status: http.StatusBadRequest, body: "parse error"},
+		{name: "result type", status: http.StatusOK, body: `{"status":"success","data":{"resultType":"streams","result":[]}}`},
+		{name: "invalid value", status: http.StatusOK, body: `{"status":"success","data":{"resultType":"matrix","result":[{"metric":{},"values":[[1577836800,"abc"]]}]}}`},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			client, err := NewLokiClient(Datasource{URL: server.URL})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := client.GetData(context.Background(), []string{`{app="api"}`}, []string{""}, time.Unix(1577836800, 0), time.Unix(1577836860, 0)); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestLokiGetTableData(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/query" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		switch r.URL.Query().Get("query") {
+		case "requests":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"app":"api","level":"info"},"value":[1577836800,"10"]},
+				{"metric":{"app":"web","level":"info"},"value":[1577836800,"20"]}
+			]}}`)
+		case "errors":
+			fmt.Fprint(w, `{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"app":"api"},"value":[1577836800,"2"]}
+			]}}`)
+		default:
+			t.Errorf("unexpected query %s", r.URL.Query().Get("query"))
+		}
+	}))
+	defer server.Close()
+
+	client, err := NewLokiClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tableData, err := client.GetTableData(context.Background(), []string{"requests", "errors"}, []string{"{{.app}}", "{{.app}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*tableData) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(*tableData))
+	}
+
+	api := (*tableData)["api"]
+	if api["level"] != "info" || api["value_0"] != 10.0 || api["value_1"] != 2.0 {
+		t.Errorf("unexpected row %v", api)
+	}
+
+	web := (*tableData)["web"]
+	if web["value_0"] != 20.0 || web["value_1"] != nil {
+		t.Errorf("unexpected row %v", web)
+	}
+}
+
+func TestLokiGetLogs(t *testing.T) {
+	start := time.Unix(1577836800, 0)
+	end := time.Unix(1577836860, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/loki/api/v1/query_range" {
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+
+		query := r.URL.Query()
+		if query.Get("limit") != "2" || query.Get("direction") != "backward" || query.Get("start") != "1577836800000000000" {
+			t.Errorf("unexpected parameters %v", query)
+		}
+
+		fmt.Fprint(w, `{"status":"success","data":{"resultType":"streams","result":[
+			{"stream":{"pod":"api-1"},"values":[["1577836850000000000","third"],["1577836810000000000","first"]]},
+			{"stream":{"pod":"api-2"},"values":[["1577836830000000000","second"]]}
+		]}}`)
+	}))
+	defer server.Close()
+
+	client, err := NewLokiClient(Datasource{URL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The streams contain three log lines, but only the newest two log lines must be returned, sorted by their
+	// timestamp.
+	lines, err := client.GetLogs(context.Background(), []string{`{app="api"}`}, []string{"{{.pod}}"}, start, end, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 log lines, got %d", len(lines))
+	}
+
+	if lines[0].Line != "second" || lines[0].Label != "api-2" || !lines[0].Timestamp.Equal(time.Unix(1577836830, 0)) {
+		t.Errorf("unexpected log line %v", lines[0])
+	}
+
+	if lines[1].Line != "third" || lines[1].Label != "api-1" || !lines[1].Timestamp.Equal(time.Unix(1577836850, 0)) {
+		t.Errorf("unexpected log line %v", lines[1])
+	}
+}
@@ -21,6 +21,8 @@ type Prometheus struct {
 	options Options
 }
 
+type stepKey struct{}
+
 type basicAuthTransport struct {
 	Transport http.RoundTripper
 
@@ -45,32 +47,7 @@ func (tat tokenAuthTransporter) RoundTrip(req *http.Request) (*http.Response, er
 }
 
 func NewPrometheusClient(datasource Datasource) (*Prometheus, error) {
-	var roundTripper http.RoundTripper = &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		DialContext: (&net.Dialer{
-			Timeout:   30 * time.Second,
-			KeepAlive: 30 * time.Second,
-		}).DialContext,
-		TLSHandshakeTimeout: 10 * time.Second,
-		TLSClientConfig: &tls.Config{
-			InsecureSkipVerify: datasource.Auth.InsecureSkipTLSVerify,
-		},
-	}
-
-	if datasource.Auth.Username != "" && datasource.Auth.Password != "" {
-		roundTripper = basicAuthTransport{
-			Transport: roundTripper,
-			username:  datasource.Auth.Username,
-			password:  datasource.Auth.Password,
-		}
-	}
-
-	if datasource.Auth.Token != "" {
-		roundTripper = tokenAuthTransporter{
-			Transport: roundTripper,
-			token:     datasource.Auth.Token,
-		}
-	}
+	roundTripper := newRoundTripper(datasource.Auth)
 
 	client, err := api.NewClient(api.Config{
 		Address:      datasource.URL,
@@ -87,8 +64,8 @@ func NewPrometheusClient(datasource Datasource) (*Prometheus, error) {
 	}, nil
 }
 
-func (p *Prometheus) GetVariableValues(query, label string, start, end time.Time) ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (p *Prometheus) GetVariableValues(ctx context.Context, query, label string, start, end time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(p.options))
 	defer cancel()
 
 	labelSets, _, err := p.v1api.Series(ctx, []string{query}, start, end)
@@ -107,15 +84,15 @@ func (p *Prometheus) GetVariableValues(query, label string, start, end time.Time
 	return values, nil
 }
 
-func (p *Prometheus) GetData(queries, labels []string, start, end time.Time) (*Data, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (p *Prometheus) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*Data, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(p.options))
 	defer cancel()
 
 	var series []Series
-	var timestamps map[int]string
-	timestamps = make(map[int]string)
+	var times []time.Time
 
-	timeRange := getTimeRange(p.options, start, end)
+	timeRange := getTimeRange(ctx, p.options, start, end)
+	traceAligned(ctx, timeRange.Step)
 
 	for i, query := range queries {
 		result, _, err := p.v1api.QueryRange(ctx, query, timeRange)
@@ -139,28 +116,29 @@ func (p *Prometheus) GetData(queries, labels []string, start, end time.Time) (*D
 				returnedLabels[string(key)] = string(value)
 			}
 
-			for key, value := range d.Values {
+			for _, value := range d.Values {
 				if i == 0 && j == 0 {
-					timestamps[key] = value.Timestamp.Time().Format("01/02 15:04")
+					times = append(times, value.Timestamp.Time())
 				}
 				points = append(points, float64(value.Value))
 			}
 
 			series = append(series, Series{
 				Label:  getLabel(labels[i], returnedLabels),
+				Labels: returnedLabels,
 				Points: points,
 			})
 		}
 	}
 
 	return &Data{
-		Timestamps: timestamps,
-		Series:     series,
+		Times:  times,
+		Series: series,
 	}, nil
 }
 
-func (p *Prometheus) GetTableData(queries, labels []string) (*TableData, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (p *Prometheus) GetTableData(ctx context.Context, queries, labels []string) (*TableData, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(p.options))
 	defer cancel()
 
 	var tableData TableData
@@ -205,8 +183,8 @@ func (p *Prometheus) GetTableData(queries, labels []string) (*TableData, error)
 	return &tableData, nil
 }
 
-func (p *Prometheus) GetSuggestions() ([]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+func (p *Prometheus) GetSuggestions(ctx context.Context) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, getTimeout(p.options))
 	defer cancel()
 
 	result, _, err := p.v1api.LabelValues(ctx, "__name__")
@@ -222,14 +200,91 @@ func (p *Prometheus) GetSuggestions() ([]string, error) {
 	return values, nil
 }
 
-func getTimeRange(options Options, start, end time.Time) v1.Range {
+func newRoundTripper(auth Auth) http.RoundTripper {
+	var roundTripper http.RoundTripper = &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: auth.InsecureSkipTLSVerify,
+		},
+	}
+
+	if auth.Username != "" && auth.Password != "" {
+		roundTripper = basicAuthTransport{
+			Transport: roundTripper,
+			username:  auth.Username,
+			password:  auth.Password,
+		}
+	}
+
+	if auth.Token != "" {
+		roundTripper = tokenAuthTransporter{
+			Transport: roundTripper,
+			token:     auth.Token,
+		}
+	}
+
+	return roundTripper
+}
+
+// WithStep returns a context, which overwrites the step calculated from the options of a datasource. This is used to
+// fetch only a part of a time range, with the same step as it was used for the whole time range.
+func WithStep(ctx context.Context, step time.Duration) context.Context {
+	return context.WithValue(ctx, stepKey{}, step)
+}
+
+// Trace contains the time range and step, which was used by a datasource for a query. The time range and step are only
+// set by datasources, which are using a step for their queries. Aligned is only true for datasources, where the
+// returned points are aligned to the step (e.g. Prometheus), so that the new points of a time series can be loaded
+// with WithStep. Other datasources are only using the step for macros or as hint for the number of points.
+type Trace struct {
+	Start   time.Time
+	End     time.Time
+	Step    time.Duration
+	Aligned bool
+}
+
+type traceKey struct{}
+
+// WithTrace returns a copy of the given context, which records the time range and step of a query in the given trace.
+func WithTrace(ctx context.Context, trace *Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// TraceFromContext returns the trace of the given context, which was added via WithTrace.
+func TraceFromContext(ctx context.Context) (*Trace, bool) {
+	trace, ok := ctx.Value(traceKey{}).(*Trace)
+	return trace, ok
+}
+
+// traceAligned records in the trace of the given context, that the returned points are aligned to the given step.
+func traceAligned(ctx context.Context, step time.Duration) {
+	if trace, ok := ctx.Value(traceKey{}).(*Trace); ok {
+		trace.Step = step
+		trace.Aligned = true
+	}
+}
+
+func getTimeRange(ctx context.Context, options Options, start, end time.Time) v1.Range {
 	var step = 10 * time.Second
-	if options.MaxPoints != 0 {
+	if s, ok := ctx.Value(stepKey{}).(time.Duration); ok && s > 0 {
+		step = s
+	} else if options.MaxPoints != 0 {
 		step = time.Duration((end.Unix()-start.Unix())/options.MaxPoints) * time.Second
 	} else if options.Step != 0 {
 		step = time.Duration(options.Step) * time.Second
 	}
 
+	if trace, ok := ctx.Value(traceKey{}).(*Trace); ok {
+		trace.Start = start
+		trace.End = end
+		trace.Step = step
+	}
+
 	return v1.Range{
 		Start: start,
 		End:   end,
@@ -237,6 +292,24 @@ func getTimeRange(options Options, start, end time.Time) v1.Range {
 	}
 }
 
+// getTimeout returns the timeout for the requests against a datasource. If no timeout is set via the options of the
+// datasource a timeout of 60 seconds is used.
+func getTimeout(options Options) time.Duration {
+	if options.Timeout > 0 {
+		return time.Duration(options.Timeout) * time.Second
+	}
+
+	return 60 * time.Second
+}
+
+// getLookbackRange returns the time range for the table data of datasources, which do not support instant queries like
+// Prometheus. To get a similar behaviour we are using the same lookback period of five minutes as Prometheus, so that
+// the tables are showing the last value of the last five minutes.
+func getLookbackRange() (time.Time, time.Time) {
+	end := time.Now()
+	return end.Add(-5 * time.Minute), end
+}
+
 func getLabel(label string, labels map[string]string) string {
 	value, err := QueryInterpolation(label, labels)
 	if err != nil || label == "" {
@@ -0,0 +1,106 @@
+package datasource
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newSQLiteTestClient(t *testing.T) *SQL {
+	t.Helper()
+
+	client, err := NewSQLClient(Datasource{Driver: "sqlite", URL: "file:" + filepath.Join(t.TempDir(), "dash.db"), Options: Options{Step: 60}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	statements := []string{
+		"CREATE TABLE requests (created_at TEXT, status TEXT, count INTEGER)",
+		"INSERT INTO requests VALUES ('2020-01-01 00:00:00', '200', 10), ('2020-01-01 00:01:00', '200', 12), ('2020-01-01 00:02:00', '200', 8)",
+		"INSERT INTO requests VALUES ('2020-01-01 00:00:00', '500', 1), ('2020-01-01 00:02:00', '500', 3)",
+	}
+
+	for _, statement := range statements {
+		if _, err := client.db.Exec(statement); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return client
+}
+
+func TestSQLGetData(t *testing.T) {
+	client := newSQLiteTestClient(t)
+	defer client.db.Close()
+
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(2 * time.Minute)
+
+	data, err := client.GetData(context.Background(), []string{"SELECT created_at AS time, status AS metric, count AS value FROM requests WHERE created_at BETWEEN $__timeFrom AND $__timeTo ORDER BY 1"}, []string{"status {{.metric}}"}, start, end)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(data.Times) != 3 || !data.Times[0].Equal(start) || !data.Times[2].Equal(end) {
+		t.Fatalf("unexpected times %v", data.Times)
+	}
+
+	if len(data.Series) != 2 {
+		t.Fatalf("expected 2 series, got %d", len(data.Series))
+	}
+
+	if data.Series[0].Label != "status 200" || data.Series[0].Labels["metric"] != "200" {
+		t.Errorf("unexpected series %v", data.Series[0])
+	}
+
+	expected := []float64{10, 12, 8}
+	for index, point := range data.Series[0].Points {
+		if point != expected[index] {
+			t.Errorf("unexpected points %v, expected %v", data.Series[0].Points, expected)
+			break
+		}
+	}
+
+	// The second series has no value for the second timestamp, which must be returned as missing value.
+	if points := data.Series[1].Points; len(points) != 3 || points[0] != 1 || !math.IsNaN(points[1]) || points[2] != 3 {
+		t.Errorf("unexpected points %v", points)
+	}
+}
+
+func TestSQLGetTableData(t *testing.T) {
+	client := newSQLiteTestClient(t)
+	defer client.db.Close()
+
+	data, err := client.GetTableData(context.Background(), []string{"SELECT status, sum(count) AS value FROM requests GROUP BY status"}, []string{"{{.status}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(*data) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(*data))
+	}
+
+	if value := (*data)["200"]["value_0"]; value != float64(30) {
+		t.Errorf("unexpected value %v for status 200", value)
+	}
+
+	if value := (*data)["500"]["value_0"]; value != float64(4) {
+		t.Errorf("unexpected value %v for status 500", value)
+	}
+}
+
+func TestSQLGetSuggestions(t *testing.T) {
+	client := newSQLiteTestClient(t)
+	defer client.db.Close()
+
+	suggestions, err := client.GetSuggestions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(suggestions) != 1 || suggestions[0] != "requests" {
+		t.Errorf("unexpected suggestions %v", suggestions)
+	}
+}
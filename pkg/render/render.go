@@ -3,6 +3,8 @@ package render
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
 	"strconv"
 	"time"
 
@@ -23,7 +25,7 @@ var (
 	ErrNoDashboards = errors.New("no dashboards were provided")
 )
 
-func Run(explore bool, datasources map[string]datasource.Client, dashboards []dashboard.Dashboard, initialInterval, initialRefresh string) error {
+func Run(explore bool, datasources map[string]datasource.Client, dashboards []dashboard.Dashboard, initialInterval, initialRefresh, initialTimezone string, concurrency int, alertsExec string, alertsBell bool) error {
 	// Check if there was at least one dashboard provided. This is required for the storage implementation, because we
 	// choose the first dashboard as the initial one.
 	// When the check succeeded we create the storage, which holds the current state of dash.
@@ -31,10 +33,16 @@ func Run(explore bool, datasources map[string]datasource.Client, dashboards []da
 		return ErrNoDashboards
 	}
 
-	storage, err := utils.NewStorage(explore, datasources, dashboards, initialInterval, initialRefresh)
+	// The context is used for all queries against the datasources and to stop termdash. When the context is canceled
+	// all running queries are canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	storage, err := utils.NewStorage(ctx, explore, datasources, dashboards, initialInterval, initialRefresh, initialTimezone)
 	if err != nil {
 		return err
 	}
+	storage.Alerts = utils.NewAlerts(alertsExec, alertsBell)
 
 	// Initialize termdash.
 	// We create the statusbar, modal and the grid. The initial view shows the statusbar and grid. If an item from the
@@ -49,23 +57,28 @@ func Run(explore bool, datasources map[string]datasource.Client, dashboards []da
 	if err != nil {
 		return err
 	}
+	// The statusbar is updated each time the state of the alerts changes, because the alerts are evaluated when the
+	// graphs are loaded in the background.
+	storage.Alerts.OnChange(func() {
+		statusbar.Update(t.Size().X)
+	})
 	modal, err := widget.NewModal(storage)
 	if err != nil {
 		return err
 	}
 
-	gridOpts := widget.GridLayout(storage)
+	gridLayout := widget.NewGrid(storage, concurrency, t.Size)
+	gridOpts := gridLayout.Layout()
 
 	c, err := container.New(t, container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(gridOpts...), container.SplitFixed(1)), container.ID("layout"))
 	if err != nil {
 		return err
 	}
+	gridLayout.Load(c)
 
 	var modalActive bool
 	var previousKey keyboard.Key
 
-	ctx, cancel := context.WithCancel(context.Background())
-
 	ticker := time.NewTicker(storage.GetRefresh())
 	defer ticker.Stop()
 	go func() {
@@ -75,9 +88,10 @@ func Run(explore bool, datasources map[string]datasource.Client, dashboards []da
 				if !modalActive {
 					fLog.Debugf("refresh was triggered")
 					storage.RefreshInterval()
-					gridOpts = widget.GridLayout(storage)
-					c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(gridOpts...), container.SplitFixed(1)))
+					gridLayout.Load(c)
 				}
+			case <-storage.Alerts.Bell():
+				fmt.Fprint(os.Stdout, "\a")
 			case <-ctx.Done():
 				return
 			}
@@ -87,30 +101,32 @@ func Run(explore bool, datasources map[string]datasource.Client, dashboards []da
 	keyboardSubscriber := func(k *terminalapi.Keyboard) {
 		fLog.Debugf("key %s was pressed", k.Key)
 		switch k.Key {
-		case 'q', keyboard.KeyCtrlC:
+		case keyboard.KeyCtrlC:
 			cancel()
+		case 'q':
+			// dash is stopped via "q", except when a modal with a text input is active, where the key is used as input
+			// for the modal.
+			if modalActive && (explore || modal.AcceptsText()) {
+				modal.SelectIndex(string(k.Key))
+			} else {
+				cancel()
+			}
 		case keyboard.KeyEnter:
 			if modalActive {
 				modalType, err := modal.Select()
 				if err == nil {
-					if modalType == widget.ModalTypeDatasource {
-						storage.RefreshInterval()
-					} else if modalType == widget.ModalTypeDashboard {
-						storage.RefreshInterval()
-					} else if modalType == widget.ModalTypeVariable {
-						storage.RefreshInterval()
-					} else if modalType == widget.ModalTypeInterval {
-						storage.RefreshInterval()
-					} else if modalType == widget.ModalTypeRefresh {
-						ticker = time.NewTicker(storage.GetRefresh())
-					} else if modalType == widget.ModalTypeExplore {
+					// The time range is kept when a modal is closed, so that queries which are not affected by the
+					// selection are returned from the cache. Only a changed refresh interval refreshes the dashboard.
+					if modalType == widget.ModalTypeRefresh {
+						ticker.Reset(storage.GetRefresh())
 						storage.RefreshInterval()
 					}
 
 					modalActive = false
 					statusbar.Update(t.Size().X)
-					gridOpts = widget.GridLayout(storage)
+					gridOpts = gridLayout.Layout()
 					c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(gridOpts...), container.SplitFixed(1)))
+					gridLayout.Load(c)
 				}
 			}
 		case keyboard.KeyF1:
@@ -146,18 +162,131 @@ func Run(explore bool, datasources map[string]datasource.Client, dashboards []da
 		case keyboard.KeyF5:
 			modalActive = modal.Show(&widget.ModalOptions{Type: widget.ModalTypeRefresh, VariableIndex: 0})
 			c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(container.PlaceWidget(modal)), container.SplitFixed(1)))
+		case keyboard.KeyF6:
+			modalActive = modal.Show(&widget.ModalOptions{Type: widget.ModalTypeAlerts, VariableIndex: 0})
+			c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(container.PlaceWidget(modal)), container.SplitFixed(1)))
+		case keyboard.KeyF7:
+			modalActive = modal.Show(&widget.ModalOptions{Type: widget.ModalTypeInspect, VariableIndex: 0})
+			c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(container.PlaceWidget(modal)), container.SplitFixed(1)))
 		case keyboard.KeyEsc:
+			// If no modal is active, Esc disables the cursor or leaves the full screen mode of the focused graph.
+			if !modalActive && gridLayout.CursorActive() {
+				gridLayout.ToggleCursor(c)
+				break
+			}
+			// Otherwise Esc closes the modal or refreshes the dashboard. Only an explicit refresh moves the time range
+			// and removes the cached results, so that closing a modal doesn't send all queries again.
+			if !modalActive && gridLayout.Fullscreen() {
+				gridLayout.ToggleFullscreen()
+			} else if !modalActive {
+				storage.FlushCache()
+				storage.RefreshInterval()
+			}
+
 			modalActive = false
-			storage.RefreshInterval()
-			gridOpts = widget.GridLayout(storage)
+			gridOpts = gridLayout.Layout()
 			c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(gridOpts...), container.SplitFixed(1)))
+			gridLayout.Load(c)
 		case keyboard.KeyBackspace, keyboard.KeyBackspace2, keyboard.KeyDelete:
 			if modalActive {
 				modal.RemoveIndex()
 			}
-		default:
-			if explore {
+		case '[', ']', '+', '=', '-':
+			// The time range can be moved back and forward via "[" and "]" and zoomed in and out via "+" and "-". If a
+			// modal is active, the keys are used as input for the modal.
+			if modalActive {
+				if explore || modal.AcceptsText() {
+					modal.SelectIndex(string(k.Key))
+				}
+			} else {
+				var err error
+				switch k.Key {
+				case '[':
+					err = storage.ShiftInterval(-0.5)
+				case ']':
+					err = storage.ShiftInterval(0.5)
+				case '+', '=':
+					err = storage.ZoomInterval(0.5)
+				case '-':
+					err = storage.ZoomInterval(2)
+				}
+
+				if err == nil {
+					statusbar.Update(t.Size().X)
+					gridLayout.Load(c)
+				}
+			}
+		case keyboard.KeyArrowUp, keyboard.KeyArrowDown, keyboard.KeyArrowLeft, keyboard.KeyArrowRight:
+			// The arrow keys are moving the focus between the graphs. If the focused graph is shown in full screen, the
+			// newly focused graph is shown in full screen. If the cursor is active, left and right are moving the cursor.
+			if !modalActive {
+				if gridLayout.CursorActive() && (k.Key == keyboard.KeyArrowLeft || k.Key == keyboard.KeyArrowRight) {
+					if k.Key == keyboard.KeyArrowLeft {
+						gridLayout.MoveCursor(c, -1)
+					} else {
+						gridLayout.MoveCursor(c, 1)
+					}
+					break
+				}
+
+				var relayout bool
+				switch k.Key {
+				case keyboard.KeyArrowUp:
+					relayout = gridLayout.MoveFocus(c, -1, 0)
+				case keyboard.KeyArrowDown:
+					relayout = gridLayout.MoveFocus(c, 1, 0)
+				case keyboard.KeyArrowLeft:
+					relayout = gridLayout.MoveFocus(c, 0, -1)
+				case keyboard.KeyArrowRight:
+					relayout = gridLayout.MoveFocus(c, 0, 1)
+				}
+
+				if relayout {
+					gridOpts = gridLayout.Layout()
+					c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(gridOpts...), container.SplitFixed(1)))
+					gridLayout.Load(c)
+				}
+			}
+		case 'c':
+			// The cursor on the focused graph is enabled and disabled via "c". If a modal is active, the key is used as
+			// input for the modal.
+			if modalActive {
+				if explore || modal.AcceptsText() {
+					modal.SelectIndex(string(k.Key))
+				}
+			} else {
+				gridLayout.ToggleCursor(c)
+			}
+		case 'f':
+			// The focused graph is shown in full screen via "f". If a modal is active, the key is used as input for the
+			// modal.
+			if modalActive {
+				if explore || modal.AcceptsText() {
+					modal.SelectIndex(string(k.Key))
+				}
+			} else {
+				gridLayout.ToggleFullscreen()
+				gridOpts = gridLayout.Layout()
+				c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(gridOpts...), container.SplitFixed(1)))
+				gridLayout.Load(c)
+			}
+		case 's':
+			// The series of the focused graph can be hidden, isolated and filtered via "s". If a modal is active, the key
+			// is used as input for the modal.
+			if modalActive {
+				if explore || modal.AcceptsText() {
+					modal.SelectIndex(string(k.Key))
+				}
+			} else {
+				graphID, series := gridLayout.FocusedSeries()
+				modalActive = modal.Show(&widget.ModalOptions{Type: widget.ModalTypeSeries, GraphID: graphID, Series: series})
 				if modalActive {
+					c.Update("layout", container.SplitHorizontal(container.Top(container.PlaceWidget(statusbar)), container.Bottom(container.PlaceWidget(modal)), container.SplitFixed(1)))
+				}
+			}
+		default:
+			if modalActive {
+				if explore || modal.AcceptsText() {
 					modal.SelectIndex(string(k.Key))
 				}
 			}
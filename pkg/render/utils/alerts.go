@@ -0,0 +1,232 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/dashboard"
+	fLog "github.com/ricoberger/dash/pkg/log"
+)
+
+type AlertState string
+
+const (
+	AlertStatePending AlertState = "pending"
+	AlertStateFiring  AlertState = "firing"
+)
+
+// AlertResult is the result of the evaluation of an alert for a single series of a graph.
+type AlertResult struct {
+	Alert  dashboard.Alert
+	Series string
+	Value  float64
+	Active bool
+}
+
+// AlertInstance is an active alert for a single series of a graph.
+type AlertInstance struct {
+	Dashboard string
+	Graph     string
+	Alert     dashboard.Alert
+	Series    string
+	Value     float64
+	State     AlertState
+	ActiveAt  time.Time
+}
+
+// Alerts contains the state of all alerts of the active dashboard. When an alert starts firing, the terminal bell is
+// rung and the exec hook is run, if they are enabled. The bell is not rung by Alerts, because the alerts are evaluated
+// in the background while termbox owns the terminal, instead a notification is sent to the Bell channel.
+type Alerts struct {
+	mu        sync.Mutex
+	instances map[string]*AlertInstance
+	exec      string
+	bell      bool
+	bells     chan struct{}
+	onChange  func()
+}
+
+// NewAlerts returns a new Alerts object. If exec is not empty, the command is run via "sh -c" for each alert which
+// starts firing. The alert is passed to the command via the DASH_ALERT_* environment variables.
+func NewAlerts(exec string, bell bool) *Alerts {
+	return &Alerts{
+		instances: make(map[string]*AlertInstance),
+		exec:      exec,
+		bell:      bell,
+		bells:     make(chan struct{}, 1),
+	}
+}
+
+// Bell returns a channel, which receives a value when the terminal bell should be rung. Multiple alerts which are
+// starting to fire before the channel is drained are only resulting in one value.
+func (a *Alerts) Bell() <-chan struct{} {
+	return a.bells
+}
+
+// OnChange sets a function, which is called each time the state of the alerts changes.
+func (a *Alerts) OnChange(fn func()) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.onChange = fn
+}
+
+// Update updates the state of the alerts for a graph with the results of the last evaluation. Alerts of the graph,
+// which are not contained in the results anymore are resolved.
+func (a *Alerts) Update(dashboardName, graphID, graphTitle string, results []AlertResult, now time.Time) {
+	a.mu.Lock()
+
+	changed := false
+	prefix := fmt.Sprintf("%s|%s|", dashboardName, graphID)
+	active := make(map[string]bool)
+	var fired []AlertInstance
+
+	for _, result := range results {
+		if !result.Active {
+			continue
+		}
+
+		key := fmt.Sprintf("%s%s|%s", prefix, result.Alert.Name, result.Series)
+		active[key] = true
+
+		instance, ok := a.instances[key]
+		if !ok {
+			instance = &AlertInstance{
+				Dashboard: dashboardName,
+				Graph:     graphTitle,
+				Alert:     result.Alert,
+				Series:    result.Series,
+				State:     AlertStatePending,
+				ActiveAt:  now,
+			}
+			a.instances[key] = instance
+			changed = true
+		}
+
+		instance.Value = result.Value
+
+		forDuration, err := result.Alert.GetFor()
+		if err != nil {
+			fLog.Debugf("invalid for duration for alert %s: %s", result.Alert.Name, err.Error())
+		}
+
+		if instance.State == AlertStatePending && now.Sub(instance.ActiveAt) >= forDuration {
+			instance.State = AlertStateFiring
+			fired = append(fired, *instance)
+			changed = true
+		}
+	}
+
+	for key := range a.instances {
+		if strings.HasPrefix(key, prefix) && !active[key] {
+			delete(a.instances, key)
+			changed = true
+		}
+	}
+
+	onChange := a.onChange
+	a.mu.Unlock()
+
+	for _, instance := range fired {
+		a.notify(instance)
+	}
+
+	if changed && onChange != nil {
+		onChange()
+	}
+}
+
+// Retain removes all alerts which are not for the given dashboard. Only the graphs of the active dashboard are loaded,
+// so that the alerts of other dashboards would never be resolved.
+func (a *Alerts) Retain(dashboardName string) {
+	a.mu.Lock()
+
+	changed := false
+	for key, instance := range a.instances {
+		if instance.Dashboard != dashboardName {
+			delete(a.instances, key)
+			changed = true
+		}
+	}
+
+	onChange := a.onChange
+	a.mu.Unlock()
+
+	if changed && onChange != nil {
+		onChange()
+	}
+}
+
+// List returns all active alerts. Firing alerts are returned before pending alerts and alerts with the same state are
+// sorted by the time they became active.
+func (a *Alerts) List() []AlertInstance {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var instances []AlertInstance
+	for _, instance := range a.instances {
+		instances = append(instances, *instance)
+	}
+
+	sort.Slice(instances, func(i, j int) bool {
+		if instances[i].State != instances[j].State {
+			return instances[i].State == AlertStateFiring
+		}
+
+		return instances[i].ActiveAt.Before(instances[j].ActiveAt)
+	})
+
+	return instances
+}
+
+// Count returns the number of firing and pending alerts.
+func (a *Alerts) Count() (int, int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var firing, pending int
+	for _, instance := range a.instances {
+		if instance.State == AlertStateFiring {
+			firing++
+		} else {
+			pending++
+		}
+	}
+
+	return firing, pending
+}
+
+func (a *Alerts) notify(instance AlertInstance) {
+	fLog.Debugf("alert %s for %s in %s is firing", instance.Alert.Name, instance.Series, instance.Graph)
+
+	if a.bell {
+		select {
+		case a.bells <- struct{}{}:
+		default:
+		}
+	}
+
+	if a.exec != "" {
+		go func() {
+			cmd := exec.Command("sh", "-c", a.exec)
+			cmd.Env = append(os.Environ(),
+				"DASH_ALERT_NAME="+instance.Alert.Name,
+				"DASH_ALERT_SEVERITY="+instance.Alert.Severity,
+				"DASH_ALERT_DASHBOARD="+instance.Dashboard,
+				"DASH_ALERT_GRAPH="+instance.Graph,
+				"DASH_ALERT_SERIES="+instance.Series,
+				"DASH_ALERT_VALUE="+strconv.FormatFloat(instance.Value, 'f', -1, 64),
+			)
+
+			if err := cmd.Run(); err != nil {
+				fLog.Debugf("could not run exec hook for alert %s: %s", instance.Alert.Name, err.Error())
+			}
+		}()
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/dashboard"
+)
+
+func TestAlertsUpdate(t *testing.T) {
+	alerts := NewAlerts("", true)
+
+	var changes int
+	alerts.OnChange(func() {
+		changes++
+	})
+
+	rule := dashboard.Alert{Name: "HighCPU", Operator: ">", Value: 90, For: "5m"}
+	now := time.Unix(1577836800, 0)
+
+	// An active alert is pending until it was active for the for duration.
+	alerts.Update("dashboard", "graph-0-0", "CPU", []AlertResult{{Alert: rule, Series: "server-1", Value: 95, Active: true}}, now)
+	if firing, pending := alerts.Count(); firing != 0 || pending != 1 || changes != 1 {
+		t.Fatalf("expected 1 pending alert and 1 change, got %d firing, %d pending and %d changes", firing, pending, changes)
+	}
+
+	alerts.Update("dashboard", "graph-0-0", "CPU", []AlertResult{{Alert: rule, Series: "server-1", Value: 96, Active: true}}, now.Add(4*time.Minute))
+	if firing, pending := alerts.Count(); firing != 0 || pending != 1 || changes != 1 {
+		t.Fatalf("expected 1 pending alert and 1 change, got %d firing, %d pending and %d changes", firing, pending, changes)
+	}
+
+	select {
+	case <-alerts.Bell():
+		t.Fatal("the bell must not be rung for pending alerts")
+	default:
+	}
+
+	// The alert starts firing after the for duration and rings the bell once.
+	alerts.Update("dashboard", "graph-0-0", "CPU", []AlertResult{{Alert: rule, Series: "server-1", Value: 97, Active: true}}, now.Add(5*time.Minute))
+	instances := alerts.List()
+	if len(instances) != 1 || instances[0].State != AlertStateFiring || instances[0].Value != 97 || !instances[0].ActiveAt.Equal(now) || changes != 2 {
+		t.Fatalf("expected 1 firing alert and 2 changes, got %v and %d changes", instances, changes)
+	}
+
+	select {
+	case <-alerts.Bell():
+	default:
+		t.Fatal("expected the bell for a firing alert")
+	}
+
+	// Alerts without a for duration are firing immediately.
+	alerts.Update("dashboard", "graph-0-1", "Memory", []AlertResult{{Alert: dashboard.Alert{Name: "HighMemory"}, Series: "server-1", Active: true}}, now.Add(5*time.Minute))
+	if firing, pending := alerts.Count(); firing != 2 || pending != 0 {
+		t.Fatalf("expected 2 firing alerts, got %d firing and %d pending", firing, pending)
+	}
+
+	// Alerts which are not active anymore are resolved, but alerts of other graphs are not resolved.
+	alerts.Update("dashboard", "graph-0-0", "CPU", []AlertResult{{Alert: rule, Series: "server-1", Value: 50, Active: false}}, now.Add(6*time.Minute))
+	instances = alerts.List()
+	if len(instances) != 1 || instances[0].Alert.Name != "HighMemory" {
+		t.Fatalf("expected only the HighMemory alert, got %v", instances)
+	}
+}
@@ -1,36 +1,222 @@
 package utils
 
 import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 )
 
-func GetStartAndEndTime(interval string) (time.Time, time.Time) {
-	now := time.Now()
-
-	switch interval {
-	case "5m":
-		return now.Add(time.Duration(-5) * time.Minute), now
-	case "15m":
-		return now.Add(time.Duration(-15) * time.Minute), now
-	case "30m":
-		return now.Add(time.Duration(-30) * time.Minute), now
-	case "1h":
-		return now.Add(time.Duration(-1) * time.Hour), now
-	case "3h":
-		return now.Add(time.Duration(-3) * time.Hour), now
-	case "6h":
-		return now.Add(time.Duration(-6) * time.Hour), now
-	case "12h":
-		return now.Add(time.Duration(-12) * time.Hour), now
-	case "24h":
-		return now.Add(time.Duration(-24) * time.Hour), now
-	case "2d":
-		return now.Add(time.Duration(-48) * time.Hour), now
-	case "7d":
-		return now.Add(time.Duration(-168) * time.Hour), now
-	case "30d":
-		return now.Add(time.Duration(-720) * time.Hour), now
+const (
+	timeRangeSeparator = " to "
+	timeRangeFormat    = "2006-01-02 15:04:05"
+)
+
+var (
+	absoluteTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02T15:04:05Z07:00", "2006-01-02"}
+	timeOnlyLayouts     = []string{"15:04:05", "15:04"}
+	durationRegexp      = regexp.MustCompile(`^(\d+[smhdwMy])+$`)
+	relativeTermRegexp  = regexp.MustCompile(`^(\d+)([smhdwMy])`)
+)
+
+// ParseInterval returns the start and end time for the given interval. The interval can be a duration like "1h" or
+// "90m", which ends now or a time range in the format "<from> to <to>". If the to part is omitted "now" is used. The
+// from and to parts can be relative or absolute times, see ParseTime. If the to part is only a time of day, the date
+// of the from part is used, e.g. "2026-10-01 14:00 to 16:30".
+func ParseInterval(interval string, now time.Time) (time.Time, time.Time, error) {
+	from := strings.TrimSpace(interval)
+	to := "now"
+
+	if index := strings.Index(interval, timeRangeSeparator); index != -1 {
+		from = strings.TrimSpace(interval[:index])
+		to = strings.TrimSpace(interval[index+len(timeRangeSeparator):])
+	}
+
+	if durationRegexp.MatchString(from) {
+		from = "now-" + from
+	}
+
+	start, err := ParseTime(from, now, false)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	end, err := parseTime(to, now, start, true)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if !start.Before(end) {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid interval %s: start must be before end", interval)
+	}
+
+	return start, end, nil
+}
+
+// ParseTime parses a relative or absolute time. A relative time starts with "now" followed by any number of
+// additions or subtractions and an optional rounding to a unit, e.g. "now-90m", "now-1d+12h" or "now-2d/d". The
+// supported units are s, m, h, d, w, M and y. If roundUp is true the time is rounded to the end of the unit instead of
+// the start. An absolute time can be a unix timestamp or a time in the format "2006-01-02 15:04:05", "2006-01-02
+// 15:04", "2006-01-02T15:04:05Z07:00" or "2006-01-02". A time of day in the format "15:04:05" or "15:04" is on the
+// date of now.
+func ParseTime(value string, now time.Time, roundUp bool) (time.Time, error) {
+	return parseTime(value, now, now, roundUp)
+}
+
+// parseTime parses a relative or absolute time like ParseTime, but uses the given date for a time of day.
+func parseTime(value string, now, date time.Time, roundUp bool) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	if strings.HasPrefix(value, "now") {
+		return parseRelativeTime(value, now, roundUp)
+	}
+
+	for _, layout := range absoluteTimeLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	for _, layout := range timeOnlyLayouts {
+		if t, err := time.ParseInLocation(layout, value, now.Location()); err == nil {
+			date = date.In(now.Location())
+			return time.Date(date.Year(), date.Month(), date.Day(), t.Hour(), t.Minute(), t.Second(), 0, now.Location()), nil
+		}
+	}
+
+	if timestamp, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(timestamp, 0).In(now.Location()), nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid time %s", value)
+}
+
+func parseRelativeTime(value string, now time.Time, roundUp bool) (time.Time, error) {
+	t := now
+	expression := value[len("now"):]
+
+	for len(expression) > 0 {
+		switch expression[0] {
+		case '+', '-':
+			sign := 1
+			if expression[0] == '-' {
+				sign = -1
+			}
+
+			expression = expression[1:]
+			terms := 0
+
+			for {
+				match := relativeTermRegexp.FindStringSubmatch(expression)
+				if match == nil {
+					break
+				}
+
+				count, err := strconv.Atoi(match[1])
+				if err != nil {
+					return time.Time{}, fmt.Errorf("invalid time %s: %s", value, err.Error())
+				}
+
+				t = addUnit(t, sign*count, match[2])
+				expression = expression[len(match[0]):]
+				terms++
+			}
+
+			if terms == 0 {
+				return time.Time{}, fmt.Errorf("invalid time %s: missing duration", value)
+			}
+		case '/':
+			if len(expression) != 2 || !strings.Contains("smhdwMy", expression[1:]) {
+				return time.Time{}, fmt.Errorf("invalid time %s: invalid rounding", value)
+			}
+
+			unit := expression[1:]
+			t = startOfUnit(t, unit)
+			if roundUp {
+				t = addUnit(t, 1, unit).Add(-time.Millisecond)
+			}
+			expression = ""
+		default:
+			return time.Time{}, fmt.Errorf("invalid time %s", value)
+		}
+	}
+
+	return t, nil
+}
+
+func addUnit(t time.Time, count int, unit string) time.Time {
+	switch unit {
+	case "s":
+		return t.Add(time.Duration(count) * time.Second)
+	case "m":
+		return t.Add(time.Duration(count) * time.Minute)
+	case "h":
+		return t.Add(time.Duration(count) * time.Hour)
+	case "d":
+		return t.AddDate(0, 0, count)
+	case "w":
+		return t.AddDate(0, 0, 7*count)
+	case "M":
+		return t.AddDate(0, count, 0)
+	case "y":
+		return t.AddDate(count, 0, 0)
+	default:
+		return t
+	}
+}
+
+// startOfUnit returns the start of the unit, which contains the given time. Weeks are starting on monday.
+func startOfUnit(t time.Time, unit string) time.Time {
+	switch unit {
+	case "s":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	case "m":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, t.Location())
+	case "h":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case "d":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	case "w":
+		return time.Date(t.Year(), t.Month(), t.Day()-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case "M":
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+	case "y":
+		return time.Date(t.Year(), 1, 1, 0, 0, 0, 0, t.Location())
 	default:
-		return now.Add(time.Duration(-1) * time.Hour), now
+		return t
+	}
+}
+
+// FormatInterval returns the interval for the given start and end time. If the end time is not before now, the
+// interval is returned as duration, so that it is moved with each refresh. Otherwise an absolute time range is
+// returned.
+func FormatInterval(start, end, now time.Time) string {
+	if !end.Before(now) {
+		return formatDuration(now.Sub(start))
+	}
+
+	return start.Format(timeRangeFormat) + timeRangeSeparator + end.Format(timeRangeFormat)
+}
+
+// formatDuration formats the duration in the format which is used for intervals, e.g. "1d6h" instead of "30h0m0s".
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+
+	var value string
+	for _, unit := range []struct {
+		name     string
+		duration time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}} {
+		if count := d / unit.duration; count > 0 {
+			value = value + fmt.Sprintf("%d%s", count, unit.name)
+			d = d - count*unit.duration
+		}
 	}
+
+	if value == "" {
+		return "1s"
+	}
+
+	return value
 }
@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// now is a wednesday, so that the rounding to weeks can be tested.
+var testNow = time.Date(2026, 10, 14, 15, 30, 45, 0, time.UTC)
+
+func TestParseInterval(t *testing.T) {
+	for _, tc := range []struct {
+		interval string
+		start    time.Time
+		end      time.Time
+		err      bool
+	}{
+		{interval: "1h", start: testNow.Add(-time.Hour), end: testNow},
+		{interval: "1d12h", start: testNow.Add(-36 * time.Hour), end: testNow},
+		{interval: "now-2d/d to now-1d/d", start: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC), end: time.Date(2026, 10, 13, 23, 59, 59, 999000000, time.UTC)},
+		{interval: "now-7d", start: testNow.AddDate(0, 0, -7), end: testNow},
+		{interval: "2026-10-01 14:00 to 16:30", start: time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC), end: time.Date(2026, 10, 1, 16, 30, 0, 0, time.UTC)},
+		{interval: "2026-10-01 14:00:00 to 2026-10-02 08:00:00", start: time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC), end: time.Date(2026, 10, 2, 8, 0, 0, 0, time.UTC)},
+		{interval: "2026-10-01 to now", start: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), end: testNow},
+		{interval: "14:00 to 15:00", start: time.Date(2026, 10, 14, 14, 0, 0, 0, time.UTC), end: time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)},
+		{interval: "2026-10-01 16:30 to 14:00", err: true},
+		{interval: "now to now-1h", err: true},
+		{interval: "yesterday", err: true},
+	} {
+		t.Run(tc.interval, func(t *testing.T) {
+			start, end, err := ParseInterval(tc.interval, testNow)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %s - %s", start, end)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !start.Equal(tc.start) || !end.Equal(tc.end) {
+				t.Errorf("expected %s - %s, got %s - %s", tc.start, tc.end, start, end)
+			}
+		})
+	}
+}
+
+func TestParseTime(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		roundUp  bool
+		expected time.Time
+		err      bool
+	}{
+		{value: "now", expected: testNow},
+		{value: "2026-10-01 14:00:05", expected: time.Date(2026, 10, 1, 14, 0, 5, 0, time.UTC)},
+		{value: "2026-10-01 14:00", expected: time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC)},
+		{value: "2026-10-01T14:00:00+02:00", expected: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)},
+		{value: "2026-10-01", expected: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "16:30", expected: time.Date(2026, 10, 14, 16, 30, 0, 0, time.UTC)},
+		{value: "16:30:15", expected: time.Date(2026, 10, 14, 16, 30, 15, 0, time.UTC)},
+		{value: "1790000000", expected: time.Unix(1790000000, 0)},
+		{value: "25:00", err: true},
+		{value: "2026-13-01", err: true},
+		{value: "tomorrow", err: true},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			parsed, err := ParseTime(tc.value, testNow, tc.roundUp)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %s", parsed)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !parsed.Equal(tc.expected) {
+				t.Errorf("expected %s, got %s", tc.expected, parsed)
+			}
+		})
+	}
+}
+
+func TestParseRelativeTime(t *testing.T) {
+	for _, tc := range []struct {
+		value    string
+		roundUp  bool
+		expected time.Time
+		err      bool
+	}{
+		{value: "now", expected: testNow},
+		{value: "now-90m", expected: testNow.Add(-90 * time.Minute)},
+		{value: "now-1d+12h", expected: testNow.Add(-12 * time.Hour)},
+		{value: "now-1h30m", expected: testNow.Add(-90 * time.Minute)},
+		{value: "now-1M", expected: time.Date(2026, 9, 14, 15, 30, 45, 0, time.UTC)},
+		{value: "now+1y", expected: time.Date(2027, 10, 14, 15, 30, 45, 0, time.UTC)},
+		{value: "now/d", expected: time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)},
+		{value: "now/d", roundUp: true, expected: time.Date(2026, 10, 14, 23, 59, 59, 999000000, time.UTC)},
+		{value: "now/w", expected: time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)},
+		{value: "now-1M/M", expected: time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "now/y", expected: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "now-", err: true},
+		{value: "now-1x", err: true},
+		{value: "now/dd", err: true},
+		{value: "now*2", err: true},
+	} {
+		t.Run(tc.value, func(t *testing.T) {
+			parsed, err := parseRelativeTime(tc.value, testNow, tc.roundUp)
+			if tc.err {
+				if err == nil {
+					t.Errorf("expected an error, got %s", parsed)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !parsed.Equal(tc.expected) {
+				t.Errorf("expected %s, got %s", tc.expected, parsed)
+			}
+		})
+	}
+}
+
+func TestFormatInterval(t *testing.T) {
+	for _, tc := range []struct {
+		start    time.Time
+		end      time.Time
+		expected string
+	}{
+		{start: testNow.Add(-time.Hour), end: testNow, expected: "1h"},
+		{start: testNow.Add(-30 * time.Hour), end: testNow.Add(time.Minute), expected: "1d6h"},
+		{start: time.Date(2026, 10, 1, 14, 0, 0, 0, time.UTC), end: time.Date(2026, 10, 1, 16, 30, 0, 0, time.UTC), expected: "2026-10-01 14:00:00 to 2026-10-01 16:30:00"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			interval := FormatInterval(tc.start, tc.end, testNow)
+			if interval != tc.expected {
+				t.Fatalf("expected %s, got %s", tc.expected, interval)
+			}
+
+			// The formatted interval must be parsed to the same time range, because moving and zooming the time range
+			// relies on it. Intervals ending now are ending at the current time.
+			start, end, err := ParseInterval(interval, testNow)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expectedEnd := tc.end
+			if !tc.end.Before(testNow) {
+				expectedEnd = testNow
+			}
+
+			if !start.Equal(tc.start) || !end.Equal(expectedEnd) {
+				t.Errorf("expected %s - %s, got %s - %s", tc.start, expectedEnd, start, end)
+			}
+		})
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	for _, tc := range []struct {
+		duration time.Duration
+		expected string
+	}{
+		{duration: 0, expected: "1s"},
+		{duration: 400 * time.Millisecond, expected: "1s"},
+		{duration: 90 * time.Second, expected: "1m30s"},
+		{duration: 30 * time.Hour, expected: "1d6h"},
+		{duration: 7*24*time.Hour + 5*time.Second, expected: "7d5s"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			if value := formatDuration(tc.duration); value != tc.expected {
+				t.Errorf("expected %s, got %s", tc.expected, value)
+			}
+		})
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"fmt"
+)
+
+// SeriesFilter contains the series of a graph, which were hidden or isolated via the legend and the interactive
+// filter and top n settings, which overwrite the options of the graph.
+type SeriesFilter struct {
+	Hidden   map[string]bool
+	Isolated string
+	Regexp   string
+	TopN     int
+	Stat     string
+}
+
+// SeriesFilter returns the filter for the series of the graph with the given id in the active dashboard.
+func (s *Storage) SeriesFilter(graphID string) SeriesFilter {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.seriesFilters[s.seriesFilterKey(graphID)]
+}
+
+// SetSeriesFilter sets the filter for the series of the graph with the given id in the active dashboard.
+func (s *Storage) SetSeriesFilter(graphID string, filter SeriesFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.seriesFilters[s.seriesFilterKey(graphID)] = filter
+}
+
+func (s *Storage) seriesFilterKey(graphID string) string {
+	return fmt.Sprintf("%s|%s", s.Dashboard().Name, graphID)
+}
@@ -1,7 +1,10 @@
 package utils
 
 import (
+	"context"
+	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ricoberger/dash/pkg/dashboard"
@@ -31,8 +34,34 @@ type Storage struct {
 	ActiveDashboard  int
 	Interval         Interval
 	Refresh          string
+	Timezone         string
 	VariableValues   map[string]string
 	Explore          Explore
+	Alerts           *Alerts
+
+	mu            sync.Mutex
+	parent        context.Context
+	ctx           context.Context
+	cancel        context.CancelFunc
+	seriesFilters map[string]SeriesFilter
+}
+
+// Context returns the context for all queries against the datasources. The context is canceled, when the state of the
+// storage changes (e.g. the dashboard or interval), so that queries for the old state are not running anymore.
+func (s *Storage) Context() context.Context {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.ctx
+}
+
+// cancelQueries cancels all running queries and creates a new context for the following queries.
+func (s *Storage) cancelQueries() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cancel()
+	s.ctx, s.cancel = context.WithCancel(s.parent)
 }
 
 func (s *Storage) loadVariablesOrSuggestions() error {
@@ -45,7 +74,7 @@ func (s *Storage) loadVariablesOrSuggestions() error {
 
 func (s *Storage) loadVariables() error {
 	for _, variable := range s.Dashboards[s.ActiveDashboard].Variables {
-		values, err := variable.GetValues(s.Datasource(), s.VariableValues, s.Interval.Start, s.Interval.End)
+		values, err := variable.GetValues(s.Context(), s.Datasource(), s.VariableValues, s.Interval.Start, s.Interval.End)
 		if err != nil {
 			return err
 		}
@@ -68,7 +97,7 @@ func (s *Storage) loadVariables() error {
 }
 
 func (s *Storage) loadSuggestions() error {
-	suggestions, err := s.Datasource().GetSuggestions()
+	suggestions, err := s.Datasource().GetSuggestions(s.Context())
 	if err != nil {
 		return err
 	}
@@ -91,12 +120,55 @@ func (s *Storage) Datasource() datasource.Client {
 	return s.Datasources[s.ActiveDatasource]
 }
 
+// GraphDatasource returns the datasource for the given graph. If the graph has its own datasource this one is used, then
+// the default datasource of the active dashboard and then the active datasource. If the datasource of the graph is not
+// configured an error is returned, so that the graph doesn't silently show the data of another datasource.
+func (s *Storage) GraphDatasource(graph dashboard.Graph) (datasource.Client, error) {
+	if graph.Datasource != "" {
+		if ds, ok := s.Datasources[graph.Datasource]; ok {
+			return ds, nil
+		}
+
+		return nil, fmt.Errorf("datasource %s is not configured", graph.Datasource)
+	}
+
+	if ds, ok := s.Datasources[s.Dashboard().DefaultDatasource]; ok {
+		return ds, nil
+	}
+
+	return s.Datasource(), nil
+}
+
+// Location returns the location which is used to show and parse times. The timezone from the command-line is used
+// before the timezone of the active dashboard. If both are not set the local timezone is used.
+func (s *Storage) Location() *time.Location {
+	timezone := s.Timezone
+	if timezone == "" {
+		timezone = s.Dashboard().Timezone
+	}
+
+	location, err := LoadLocation(timezone)
+	if err != nil {
+		fLog.Debugf("could not load timezone %s: %s", timezone, err.Error())
+		return time.Local
+	}
+
+	return location
+}
+
+// now returns the current time in the location of the storage, so that absolute times are parsed and formatted in the
+// selected timezone.
+func (s *Storage) now() time.Time {
+	return time.Now().In(s.Location())
+}
+
 func (s *Storage) Dashboard() dashboard.Dashboard {
 	return s.Dashboards[s.ActiveDashboard]
 }
 
 func (s *Storage) ChangeDatasource(active string) error {
 	fLog.Debugf("change datasource to %s", active)
+	s.cancelQueries()
 	s.ActiveDatasource = active
 	s.VariableValues = make(map[string]string)
 	return s.loadVariablesOrSuggestions()
@@ -104,6 +176,7 @@ func (s *Storage) ChangeDatasource(active string) error {
 
 func (s *Storage) ChangeDashboard(active int) error {
 	fLog.Debugf("change dashboard index to %d", active)
+	s.cancelQueries()
 	s.ActiveDashboard = active
 
 	if _, ok := s.Datasources[s.Dashboards[active].DefaultDatasource]; ok {
@@ -128,13 +201,19 @@ func (s *Storage) GetVariableValues() []string {
 
 func (s *Storage) ChangeVariable(name, value string) error {
 	fLog.Debugf("change variable %s to %s", name, value)
+	s.cancelQueries()
 	s.VariableValues[name] = value
 	return s.loadVariablesOrSuggestions()
 }
 
 func (s *Storage) ChangeInterval(interval string) error {
 	fLog.Debugf("change interval to %s", interval)
-	start, end := GetStartAndEndTime(interval)
+	start, end, err := ParseInterval(interval, s.now())
+	if err != nil {
+		return err
+	}
+
+	s.cancelQueries()
 	s.Interval.Interval = interval
 	s.Interval.Start = start
 	s.Interval.End = end
@@ -142,7 +221,11 @@ func (s *Storage) ChangeInterval(interval string) error {
 }
 
 func (s *Storage) GetRefresh() time.Duration {
-	switch s.Refresh {
+	return getRefresh(s.Refresh)
+}
+
+func getRefresh(refresh string) time.Duration {
+	switch refresh {
 	case "5s":
 		return 5 * time.Second
 	case "10s":
@@ -168,17 +251,89 @@ func (s *Storage) GetRefresh() time.Duration {
 	}
 }
 
+// getCacheTTL returns the time how long the results of the datasources are cached for the given refresh interval. The
+// results are cached for the half of the refresh interval, so that they are expired when the next refresh is
+// triggered.
+func getCacheTTL(refresh string) time.Duration {
+	return getRefresh(refresh) / 2
+}
+
+// ChangeRefresh changes the refresh interval and the ttl of the cached results. All cached results are removed,
+// because they were cached with the ttl of the old refresh interval.
 func (s *Storage) ChangeRefresh(refresh string) {
 	fLog.Debugf("change refresh to %s", refresh)
 	s.Refresh = refresh
+
+	for _, ds := range s.Datasources {
+		if cache, ok := ds.(*datasource.Cache); ok {
+			cache.SetTTL(getCacheTTL(refresh))
+		}
+	}
+
+	s.FlushCache()
 }
 
+// FlushCache removes all cached results, so that the following queries are returning the current data. The cache is
+// only flushed, when the user explicitly refreshes the dashboard or changes the refresh interval. In all other cases
+// the results are removed when their ttl is expired.
+func (s *Storage) FlushCache() {
+	for _, ds := range s.Datasources {
+		if cache, ok := ds.(*datasource.Cache); ok {
+			cache.Flush()
+		}
+	}
+}
+
+// RefreshInterval updates the start and end time for the selected interval. Running queries are canceled, because
+// they are using the old time range.
 func (s *Storage) RefreshInterval() {
-	start, end := GetStartAndEndTime(s.Interval.Interval)
+	s.cancelQueries()
+
+	start, end, err := ParseInterval(s.Interval.Interval, s.now())
+	if err != nil {
+		fLog.Debugf("could not parse interval %s: %s", s.Interval.Interval, err.Error())
+		return
+	}
+
 	s.Interval.Start = start
 	s.Interval.End = end
 }
 
+// ShiftInterval moves the time range of the selected interval by the given factor of its length, e.g. -0.5 moves the
+// time range back by the half of its length.
+func (s *Storage) ShiftInterval(factor float64) error {
+	shift := time.Duration(float64(s.Interval.End.Sub(s.Interval.Start)) * factor)
+	now := s.now()
+
+	end := s.Interval.End.Add(shift)
+	if end.After(now) {
+		shift = now.Sub(s.Interval.End)
+		end = now
+	}
+
+	return s.ChangeInterval(FormatInterval(s.Interval.Start.Add(shift), end, now))
+}
+
+// ZoomInterval changes the length of the time range of the selected interval by the given factor, e.g. 2 doubles the
+// length of the time range. The center of the time range is not changed, as long as the end time is not in the
+// future.
+func (s *Storage) ZoomInterval(factor float64) error {
+	length := s.Interval.End.Sub(s.Interval.Start)
+	center := s.Interval.Start.Add(length / 2)
+	length = time.Duration(float64(length) * factor)
+	if length < time.Minute {
+		length = time.Minute
+	}
+
+	now := s.now()
+	end := center.Add(length / 2)
+	if end.After(now) {
+		end = now
+	}
+
+	return s.ChangeInterval(FormatInterval(end.Add(-length), end, now))
+}
+
 func (s *Storage) GetSuggestions(filter string) []string {
 	lastSpace := strings.LastIndex(filter, " ")
 	if lastSpace == -1 {
@@ -188,8 +343,16 @@ func (s *Storage) GetSuggestions(filter string) []string {
 	return filterSuggestions(s.Explore.Suggestions, myFilter)
 }
 
-func NewStorage(explore bool, datasources map[string]datasource.Client, dashboards []dashboard.Dashboard, initialInterval, initialRefresh string) (*Storage, error) {
-	start, end := GetStartAndEndTime(initialInterval)
+func NewStorage(ctx context.Context, explore bool, datasources map[string]datasource.Client, dashboards []dashboard.Dashboard, initialInterval, initialRefresh, initialTimezone string) (*Storage, error) {
+	if _, err := LoadLocation(initialTimezone); err != nil {
+		return nil, fmt.Errorf("invalid timezone %s: %s", initialTimezone, err.Error())
+	}
+
+	for _, d := range dashboards {
+		if _, err := LoadLocation(d.Timezone); err != nil {
+			return nil, fmt.Errorf("invalid timezone %s in dashboard %s: %s", d.Timezone, d.Name, err.Error())
+		}
+	}
 
 	var initialActiveDatasource string
 	if _, ok := datasources[dashboards[initialActiveDashboard].DefaultDatasource]; ok {
@@ -201,24 +364,42 @@ func NewStorage(explore bool, datasources map[string]datasource.Client, dashboar
 		}
 	}
 
+	// All datasources are wrapped by a cache, so that graphs with the same queries and the modals are not running the
+	// same queries multiple times between two refreshs.
+	cachedDatasources := make(map[string]datasource.Client)
+	for name, ds := range datasources {
+		cachedDatasources[name] = datasource.NewCache(ds, getCacheTTL(initialRefresh))
+	}
+
 	s := &Storage{
-		Datasources:      datasources,
+		Datasources:      cachedDatasources,
 		Dashboards:       dashboards,
 		ActiveDatasource: initialActiveDatasource,
 		ActiveDashboard:  initialActiveDashboard,
 		Interval: Interval{
 			Interval: initialInterval,
-			Start:    start,
-			End:      end,
 		},
 		Refresh:        initialRefresh,
+		Timezone:       initialTimezone,
 		VariableValues: make(map[string]string),
 		Explore: Explore{
 			Enabled: explore,
 		},
+		Alerts:        NewAlerts("", false),
+		parent:        ctx,
+		seriesFilters: make(map[string]SeriesFilter),
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	start, end, err := ParseInterval(initialInterval, s.now())
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.loadVariablesOrSuggestions()
+	s.Interval.Start = start
+	s.Interval.End = end
+
+	err = s.loadVariablesOrSuggestions()
 	if err != nil {
 		return nil, err
 	}
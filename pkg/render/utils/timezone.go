@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// LoadLocation returns the location for the given timezone. The timezone can be "UTC", "local" or the name of a
+// location in the IANA Time Zone database, e.g. "Europe/Berlin". If the timezone is empty the local timezone is
+// returned.
+func LoadLocation(timezone string) (*time.Location, error) {
+	switch strings.ToLower(timezone) {
+	case "", "local":
+		return time.Local, nil
+	case "utc":
+		return time.UTC, nil
+	default:
+		return time.LoadLocation(timezone)
+	}
+}
+
+// TimestampFormat returns the format for the labels of the time axis. The format depends on the length of the
+// interval, so that short intervals are showing seconds and long intervals are only showing the date.
+func TimestampFormat(length time.Duration) string {
+	switch {
+	case length <= 15*time.Minute:
+		return "15:04:05"
+	case length <= 24*time.Hour:
+		return "15:04"
+	case length <= 7*24*time.Hour:
+		return "01/02 15:04"
+	case length <= 365*24*time.Hour:
+		return "01/02"
+	default:
+		return "2006-01-02"
+	}
+}
+
+// FormatTimestamps returns the labels for the time axis of a graph. The labels are formatted in the given location
+// with the format for the length of the interval.
+func FormatTimestamps(times []time.Time, length time.Duration, location *time.Location) map[int]string {
+	format := TimestampFormat(length)
+	timestamps := make(map[int]string)
+
+	for key, t := range times {
+		timestamps[key] = t.In(location).Format(format)
+	}
+
+	return timestamps
+}
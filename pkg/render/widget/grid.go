@@ -1,13 +1,20 @@
 package widget
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"log"
 	"math"
 	"math/rand"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/ricoberger/dash/pkg/dashboard"
 	"github.com/ricoberger/dash/pkg/datasource"
@@ -19,6 +26,7 @@ import (
 	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/container/grid"
 	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/barchart"
 	"github.com/mum4k/termdash/widgets/donut"
 	"github.com/mum4k/termdash/widgets/gauge"
 	"github.com/mum4k/termdash/widgets/linechart"
@@ -28,73 +36,776 @@ import (
 	"github.com/olekukonko/tablewriter"
 )
 
-func GridLayout(storage *utils.Storage) []container.Option {
+const (
+	// focusColor is the border color of the focused graph.
+	focusColor = cell.ColorCyan
+	// cursorColor is the color of the cursor on a linechart.
+	cursorColor = cell.ColorWhite
+)
+
+// fullscreenStats are the stats, which are shown in the legend of a linechart in full screen.
+var fullscreenStats = []string{"min", "max", "avg", "first", "total", "diff", "range"}
+
+// Grid renders the graphs of the active dashboard. The layout of the grid contains a placeholder for each graph, which
+// is replaced as soon as the data for the graph was loaded. The data is loaded concurrently, where the number of
+// graphs which are loaded at the same time is limited by the concurrency of the grid.
+type Grid struct {
+	mu          sync.Mutex
+	storage     *utils.Storage
+	concurrency int
+	size        func() image.Point
+	generation  int
+	data        map[string]panelData
+	focusRow    int
+	focusCol    int
+	fullscreen  bool
+	cursor      time.Time
+	cursorOn    bool
+}
+
+// panelData is the data which was loaded for a graph. The key contains all settings which were used to load the data,
+// so that the data is only reused when the settings have not changed.
+type panelData struct {
+	key  string
+	data *datasource.Data
+	step time.Duration
+}
+
+// annotations loads the annotations of the active dashboard. The annotations are only loaded once, when they are
+// needed by the first linechart, so that all linecharts are showing the same events.
+type annotations struct {
+	once   sync.Once
+	load   func() ([]dashboard.Event, error)
+	events []dashboard.Event
+	err    error
+}
+
+func (a *annotations) get() ([]dashboard.Event, error) {
+	a.once.Do(func() {
+		a.events, a.err = a.load()
+	})
+
+	return a.events, a.err
+}
+
+// marker is an annotation event, which is drawn on a linechart at the point with the given index.
+type marker struct {
+	index int
+	text  string
+	color cell.Color
+}
+
+// cursor is the position of the cursor on a linechart. The legend of the linechart shows the values at the cursor
+// instead of the current values.
+type cursor struct {
+	index int
+	label string
+}
+
+// panel contains everything which is needed to load the data for a graph. The state is copied from the storage when the
+// loading is started, so that the storage can be changed while the data is loaded.
+type panel struct {
+	ctx         context.Context
+	id          string
+	key         string
+	graph       dashboard.Graph
+	ds          datasource.Client
+	dsErr       error
+	datasources map[string]datasource.Client
+	variables   map[string]string
+	start       time.Time
+	end         time.Time
+	location    *time.Location
+	annotations *annotations
+	alerts      *utils.Alerts
+	dashboard   string
+	fullscreen  bool
+	cursor      time.Time
+	cursorOn    bool
+	cached      bool
+	filter      utils.SeriesFilter
+	explore     bool
+	size        image.Point
+	hidden      bool
+}
+
+// NewGrid returns a new grid for the given storage. The size function must return the size of the terminal, which is
+// used by graphs which must know the number of cells they can use.
+func NewGrid(storage *utils.Storage, concurrency int, size func() image.Point) *Grid {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &Grid{
+		storage:     storage,
+		concurrency: concurrency,
+		size:        size,
+		data:        make(map[string]panelData),
+	}
+}
+
+// Layout returns the layout for the active dashboard, where each graph shows a loading placeholder. The data for the
+// graphs must be loaded via Load afterwards. Graphs which are still loading for a previous layout are discarded.
+func (g *Grid) Layout() []container.Option {
+	g.mu.Lock()
+	g.generation++
+	g.data = make(map[string]panelData)
+	g.clampFocus()
+	focusRow, focusCol, fullscreen := g.focusRow, g.focusCol, g.fullscreen
+	g.mu.Unlock()
+
 	var rows []grid.Element
 
-	for _, row := range storage.Dashboard().Rows {
+	for i, row := range g.storage.Dashboard().Rows {
 		var cols []grid.Element
 
-		for _, graph := range row.Graphs {
-			var component grid.Element
+		for j, graph := range row.Graphs {
+			focused := i == focusRow && j == focusCol
+			if fullscreen && !focused {
+				continue
+			}
 
-			if graph.Type == "table" {
-				data, err := graph.GetTableData(storage.Datasource(), storage.VariableValues)
+			loading := renderLoading(graph, panelID(i, j), focused)
+			if fullscreen {
+				cols = append(cols, grid.ColWidthPerc(99, loading))
+			} else {
+				cols = append(cols, grid.ColWidthPerc(graph.Width, loading))
+			}
+		}
+
+		if fullscreen && len(cols) > 0 {
+			rows = append(rows, grid.RowHeightPerc(99, cols...))
+		} else if !fullscreen {
+			rows = append(rows, grid.RowHeightPerc(row.Height, cols...))
+		}
+	}
+
+	builder := grid.New()
+	builder.Add(rows...)
+	gridOpts, _ := builder.Build()
+	return gridOpts
+}
+
+// Load loads the data for all graphs of the active dashboard and replaces the graphs in the given container, as soon as
+// the data for a graph was loaded. Load doesn't block, so that it can be called from the keyboard handler. If Load or
+// Layout is called again before all graphs are loaded, the remaining results are discarded.
+func (g *Grid) Load(c *container.Container) {
+	g.mu.Lock()
+	g.generation++
+	generation := g.generation
+	g.mu.Unlock()
+
+	g.storage.Alerts.Retain(g.storage.Dashboard().Name)
+
+	panels := g.panels()
+	queue := make(chan panel)
+
+	go func() {
+		for _, p := range panels {
+			queue <- p
+		}
+		close(queue)
+	}()
+
+	for i := 0; i < g.concurrency; i++ {
+		go func() {
+			for p := range queue {
+				if !g.isCurrent(generation) {
+					continue
+				}
+
+				// Graphs which are hidden by the full screen mode are not rendered, but their data is still loaded
+				// when they have alerts, so that the alerts of all graphs are evaluated.
+				if p.hidden {
+					if p.hasAlerts() {
+						if _, err := g.getData(p); err != nil {
+							fLog.Debugf("could not load data for alerts of %s: %s", p.graph.Title, err.Error())
+						}
+					}
+					continue
+				}
+
+				builder := grid.New()
+				builder.Add(g.renderPanel(p))
+				opts, err := builder.Build()
 				if err != nil {
-					component = renderError(graph, fmt.Sprintf("Could not load data: %s", err.Error()))
-				} else {
-					fLog.Debugf("TableData: %v", data)
-					component, err = tablePanel(graph, data)
+					log.Printf("Could not build graph %s: %s", p.graph.Title, err.Error())
+					continue
+				}
+
+				g.mu.Lock()
+				if g.generation == generation {
+					err = c.Update(p.id, opts...)
 					if err != nil {
-						component = renderError(graph, fmt.Sprintf("Could not render singlestat %s: %s", graph.Title, err.Error()))
+						fLog.Debugf("could not update graph %s: %s", p.graph.Title, err.Error())
 					}
 				}
-			} else {
-				data, err := graph.GetData(storage.Datasource(), storage.VariableValues, storage.Interval.Start, storage.Interval.End)
+				g.mu.Unlock()
+			}
+		}()
+	}
+}
+
+// MoveFocus moves the focus by the given number of rows and columns. When the focus moves to another row, the column
+// is kept as long as the row has enough graphs. The border of the focused graph is highlighted. If the grid shows the
+// focused graph in full screen, true is returned, because the layout must be rebuilt via Layout and Load.
+func (g *Grid) MoveFocus(c *container.Container, rows, cols int) bool {
+	g.mu.Lock()
+
+	previous := panelID(g.focusRow, g.focusCol)
+	cursorOn := g.cursorOn
+	g.cursorOn = false
+	g.focusRow = g.focusRow + rows
+	g.focusCol = g.focusCol + cols
+	g.clampFocus()
+	current := panelID(g.focusRow, g.focusCol)
+
+	if g.fullscreen {
+		g.mu.Unlock()
+		return previous != current
+	}
+
+	if previous != current {
+		if err := c.Update(previous, container.BorderColor(cell.ColorDefault)); err != nil {
+			fLog.Debugf("could not update graph %s: %s", previous, err.Error())
+		}
+		if err := c.Update(current, container.BorderColor(focusColor)); err != nil {
+			fLog.Debugf("could not update graph %s: %s", current, err.Error())
+		}
+	}
+
+	g.mu.Unlock()
+
+	// The cursor is removed from the previously focused graph.
+	if cursorOn {
+		g.renderCached(c, previous)
+	}
+
+	return false
+}
+
+// ToggleCursor enables or disables the cursor on the focused graph. The cursor starts at the last point of the graph.
+// The focused graph is rendered again with the already loaded data.
+func (g *Grid) ToggleCursor(c *container.Container) {
+	g.mu.Lock()
+	g.cursorOn = !g.cursorOn
+	g.cursor = g.storage.Interval.End
+	g.mu.Unlock()
+
+	g.renderFocused(c)
+}
+
+// CursorActive returns true, when the cursor is shown on the focused graph.
+func (g *Grid) CursorActive() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.cursorOn
+}
+
+// MoveCursor moves the cursor on the focused graph by the given number of points. The focused graph is rendered again
+// with the already loaded data.
+func (g *Grid) MoveCursor(c *container.Container, points int) {
+	g.mu.Lock()
+	previous, ok := g.data[panelID(g.focusRow, g.focusCol)]
+	if !g.cursorOn || !ok || len(previous.data.Times) == 0 {
+		g.mu.Unlock()
+		return
+	}
+
+	times := previous.data.Times
+	index := cursorIndex(times, g.cursor) + points
+	if index < 0 {
+		index = 0
+	} else if index >= len(times) {
+		index = len(times) - 1
+	}
+	g.cursor = times[index]
+	g.mu.Unlock()
+
+	g.renderFocused(c)
+}
+
+// FocusedSeries returns the id of the focused graph and the labels of the series which were loaded for the graph.
+func (g *Grid) FocusedSeries() (string, []string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	id := panelID(g.focusRow, g.focusCol)
+
+	var labels []string
+	if previous, ok := g.data[id]; ok && previous.data != nil {
+		for _, series := range previous.data.Series {
+			labels = append(labels, series.Label)
+		}
+	}
+
+	return id, labels
+}
+
+// renderFocused renders the focused graph again with the already loaded data.
+func (g *Grid) renderFocused(c *container.Container) {
+	g.mu.Lock()
+	id := panelID(g.focusRow, g.focusCol)
+	g.mu.Unlock()
+
+	g.renderCached(c, id)
+}
+
+// renderCached renders the graph with the given id again with the already loaded data.
+func (g *Grid) renderCached(c *container.Container, id string) {
+	g.mu.Lock()
+	generation := g.generation
+	g.mu.Unlock()
+
+	for _, p := range g.panels() {
+		if p.id != id || p.hidden {
+			continue
+		}
+
+		p.cached = true
+
+		builder := grid.New()
+		builder.Add(g.renderPanel(p))
+		opts, err := builder.Build()
+		if err != nil {
+			log.Printf("Could not build graph %s: %s", p.graph.Title, err.Error())
+			return
+		}
+
+		g.mu.Lock()
+		if g.generation == generation {
+			err = c.Update(p.id, opts...)
+			if err != nil {
+				fLog.Debugf("could not update graph %s: %s", p.graph.Title, err.Error())
+			}
+		}
+		g.mu.Unlock()
+	}
+}
+
+// ToggleFullscreen switches between the grid with all graphs and the focused graph in full screen. The layout must be
+// rebuilt via Layout and Load afterwards.
+func (g *Grid) ToggleFullscreen() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.fullscreen = !g.fullscreen
+}
+
+// Fullscreen returns true, when the focused graph is shown in full screen.
+func (g *Grid) Fullscreen() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.fullscreen
+}
+
+// clampFocus ensures that the focus points to an existing graph of the active dashboard. It must be called with the
+// lock of the grid.
+func (g *Grid) clampFocus() {
+	rows := g.storage.Dashboard().Rows
+	if len(rows) == 0 {
+		g.focusRow, g.focusCol = 0, 0
+		return
+	}
+
+	if g.focusRow < 0 {
+		g.focusRow = 0
+	} else if g.focusRow >= len(rows) {
+		g.focusRow = len(rows) - 1
+	}
+
+	if g.focusCol >= len(rows[g.focusRow].Graphs) {
+		g.focusCol = len(rows[g.focusRow].Graphs) - 1
+	}
+	if g.focusCol < 0 {
+		g.focusCol = 0
+	}
+}
+
+func (g *Grid) isCurrent(generation int) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.generation == generation
+}
+
+func (g *Grid) panels() []panel {
+	var panels []panel
+
+	g.mu.Lock()
+	focusRow, focusCol, fullscreen := g.focusRow, g.focusCol, g.fullscreen
+	cursorTime, cursorOn := g.cursor, g.cursorOn
+	g.mu.Unlock()
+	ctx := g.storage.Context()
+	location := g.storage.Location()
+	terminalSize := g.size()
+
+	d := g.storage.Dashboard()
+	ds, _ := g.storage.GraphDatasource(dashboard.Graph{})
+	datasources := g.storage.Datasources
+	start, end := g.storage.Interval.Start, g.storage.Interval.End
+	annotationVariables := make(map[string]string)
+	for key, value := range g.storage.VariableValues {
+		annotationVariables[key] = value
+	}
+
+	a := &annotations{
+		load: func() ([]dashboard.Event, error) {
+			return d.GetAnnotations(ctx, ds, datasources, annotationVariables, start, end)
+		},
+	}
+
+	for i, row := range g.storage.Dashboard().Rows {
+		for j, graph := range row.Graphs {
+			variables := make(map[string]string)
+			for key, value := range g.storage.VariableValues {
+				variables[key] = value
+			}
+
+			ds, err := g.storage.GraphDatasource(graph)
+			key := fmt.Sprintf("%v|%v|%s|%s", graph, variables, g.storage.ActiveDatasource, g.storage.Interval.Interval)
+
+			panels = append(panels, panel{
+				ctx:         ctx,
+				id:          panelID(i, j),
+				key:         key,
+				graph:       graph,
+				ds:          ds,
+				dsErr:       err,
+				datasources: g.storage.Datasources,
+				variables:   variables,
+				start:       g.storage.Interval.Start,
+				end:         g.storage.Interval.End,
+				location:    location,
+				annotations: a,
+				alerts:      g.storage.Alerts,
+				dashboard:   d.Name,
+				fullscreen:  fullscreen,
+				cursor:      cursorTime,
+				cursorOn:    cursorOn && i == focusRow && j == focusCol,
+				filter:      g.storage.SeriesFilter(panelID(i, j)),
+				explore:     g.storage.Explore.Enabled,
+				size:        panelSize(terminalSize, row.Height, graph.Width, fullscreen),
+				hidden:      fullscreen && (i != focusRow || j != focusCol),
+			})
+		}
+	}
+
+	return panels
+}
+
+func panelID(row, col int) string {
+	return fmt.Sprintf("graph-%d-%d", row, col)
+}
+
+// panelSize returns the number of cells within the border of a graph with the given height and width in percent. The
+// first line of the terminal is used by the statusbar and a graph in full screen uses all remaining cells. The size is
+// reduced by one cell, because the nested splits of the grid can be rounded differently.
+func panelSize(terminal image.Point, height, width int, fullscreen bool) image.Point {
+	if fullscreen {
+		height, width = 100, 100
+	}
+
+	size := image.Point{
+		X: terminal.X*width/100 - 3,
+		Y: (terminal.Y-1)*height/100 - 3,
+	}
+
+	if size.X < 0 {
+		size.X = 0
+	}
+	if size.Y < 0 {
+		size.Y = 0
+	}
+
+	return size
+}
+
+// getData returns the data for a graph. If the data for the graph was already loaded with the same settings, only the
+// points since the last timestamp are loaded with the same step as before. The new points are appended to the previous
+// data and all points which are older then the start time are dropped.
+//
+// The step is the step which was used by the datasources for the previous request. Datasources which are not aligning
+// their points to a step (e.g. the Graphite, SQL or JSON datasource) are not reporting an aligned step, so that the
+// data of these graphs is always loaded for the whole time range.
+func (g *Grid) getData(p panel) (*datasource.Data, error) {
+	g.mu.Lock()
+	previous, ok := g.data[p.id]
+	g.mu.Unlock()
+
+	if p.cached && ok && previous.key == p.key {
+		return previous.data, nil
+	}
+
+	var data *datasource.Data
+	var step time.Duration
+
+	if ok && previous.key == p.key && previous.step > 0 && len(previous.data.Times) > 0 && previous.data.Times[len(previous.data.Times)-1].After(p.start) {
+		step = previous.step
+		last := previous.data.Times[len(previous.data.Times)-1]
+
+		fLog.Debugf("load data for %s since %s with step %s", p.graph.Title, last, step)
+		tail, err := p.graph.GetData(datasource.WithStep(p.ctx, step), p.ds, p.datasources, p.variables, last, p.end)
+		if err != nil {
+			return nil, err
+		}
+
+		data = datasource.AppendData(previous.data, tail, p.start)
+	} else {
+		var trace datasource.Trace
+		var err error
+		data, err = p.graph.GetData(datasource.WithTrace(p.ctx, &trace), p.ds, p.datasources, p.variables, p.start, p.end)
+		if err != nil {
+			return nil, err
+		}
+		if trace.Aligned {
+			step = trace.Step
+		}
+	}
+
+	g.mu.Lock()
+	g.data[p.id] = panelData{key: p.key, data: data, step: step}
+	g.mu.Unlock()
+
+	// The alerts are evaluated once for each loaded data and not when a graph is rendered again with the already
+	// loaded data, e.g. when the cursor is moved.
+	p.evaluateAlerts(data)
+
+	return data, nil
+}
+
+func (g *Grid) renderPanel(p panel) grid.Element {
+	graph := p.graph
+
+	// A graph in full screen has enough space to show all stats in the legend. The legend is also shown for graphs
+	// without a legend.
+	if p.fullscreen {
+		graph.Options.Stats = fullscreenStats
+		if graph.Options.Legend == "" {
+			graph.Options.Legend = "bottom"
+		}
+	}
+
+	// The legend shows the values at the cursor, so that it must be shown while the cursor is active.
+	if p.cursorOn && graph.Options.Legend == "" {
+		graph.Options.Legend = "bottom"
+	}
+
+	if p.dsErr != nil {
+		return renderError(graph, fmt.Sprintf("Could not load data: %s", p.dsErr.Error()))
+	}
+
+	var component grid.Element
+
+	if graph.Type == "table" {
+		data, err := graph.GetTableData(p.ctx, p.ds, p.datasources, p.variables)
+		if err != nil {
+			component = renderError(graph, fmt.Sprintf("Could not load data: %s", err.Error()))
+		} else {
+			fLog.Debugf("TableData: %v", data)
+			component, err = tablePanel(graph, data)
+			if err != nil {
+				component = renderError(graph, fmt.Sprintf("Could not render singlestat %s: %s", graph.Title, err.Error()))
+			}
+		}
+	} else if graph.Type == "logs" {
+		lines, err := graph.GetLogs(p.ctx, p.ds, p.datasources, p.variables, p.start, p.end)
+		if err != nil {
+			component = renderError(graph, fmt.Sprintf("Could not load data: %s", err.Error()))
+		} else {
+			fLog.Debugf("render %d log lines for %s", len(lines), graph.Title)
+			component, err = logsPanel(graph, lines, p.location)
+			if err != nil {
+				component = renderError(graph, fmt.Sprintf("Could not render logs %s: %s", graph.Title, err.Error()))
+			}
+		}
+	} else if graph.Type == "alertlist" {
+		alerts, err := graph.GetAlerts(p.ctx, p.ds, p.datasources, p.variables)
+		if err != nil {
+			component = renderError(graph, fmt.Sprintf("Could not load data: %s", err.Error()))
+		} else {
+			fLog.Debugf("render %d alerts for %s", len(alerts), graph.Title)
+			component, err = alertlistPanel(graph, alerts, p.location)
+			if err != nil {
+				component = renderError(graph, fmt.Sprintf("Could not render alertlist %s: %s", graph.Title, err.Error()))
+			}
+		}
+	} else {
+		data, err := g.getData(p)
+		if err != nil {
+			component = renderError(graph, fmt.Sprintf("Could not load data: %s", err.Error()))
+		} else {
+			fLog.Debugf("render %d for %s", len(data.Series), graph.Title)
+
+			switch graph.Type {
+			case "singlestat":
+				component, err = singlestatPanel(graph, data)
 				if err != nil {
-					component = renderError(graph, fmt.Sprintf("Could not load data: %s", err.Error()))
-				} else {
-					fLog.Debugf("render %d for %s", len(data.Series), graph.Title)
-
-					switch graph.Type {
-					case "singlestat":
-						component, err = singlestatPanel(graph, data)
-						if err != nil {
-							component = renderError(graph, fmt.Sprintf("Could not render singlestat %s: %s", graph.Title, err.Error()))
-						}
-					case "gauge":
-						component, err = gaugePanel(graph, data)
-						if err != nil {
-							component = renderError(graph, fmt.Sprintf("Could not render gauge %s: %s", graph.Title, err.Error()))
-						}
-					case "donut":
-						component, err = donutPanel(graph, data)
-						if err != nil {
-							component = renderError(graph, fmt.Sprintf("Could not render donut %s: %s", graph.Title, err.Error()))
-						}
-					case "sparkline":
-						component, err = sparklinePanel(graph, data)
-						if err != nil {
-							component = renderError(graph, fmt.Sprintf("Could not render sparkline %s: %s", graph.Title, err.Error()))
-						}
-					case "linechart":
-						component, err = linechartPanel(graph, data, storage.Explore.Enabled)
-						if err != nil {
-							component = renderError(graph, fmt.Sprintf("Could not load render linechart %s: %s", graph.Title, err.Error()))
-						}
-					}
+					component = renderError(graph, fmt.Sprintf("Could not render singlestat %s: %s", graph.Title, err.Error()))
+				}
+			case "gauge":
+				component, err = gaugePanel(graph, data)
+				if err != nil {
+					component = renderError(graph, fmt.Sprintf("Could not render gauge %s: %s", graph.Title, err.Error()))
+				}
+			case "donut":
+				component, err = donutPanel(graph, data)
+				if err != nil {
+					component = renderError(graph, fmt.Sprintf("Could not render donut %s: %s", graph.Title, err.Error()))
+				}
+			case "sparkline":
+				component, err = sparklinePanel(graph, data)
+				if err != nil {
+					component = renderError(graph, fmt.Sprintf("Could not render sparkline %s: %s", graph.Title, err.Error()))
+				}
+			case "barchart":
+				component, err = barchartPanel(graph, data, visibleSeries(graph, data, p.filter))
+				if err != nil {
+					component = renderError(graph, fmt.Sprintf("Could not render barchart %s: %s", graph.Title, err.Error()))
+				}
+			case "heatmap":
+				component, err = heatmapPanel(graph, data, p.location, p.size)
+				if err != nil {
+					component = renderError(graph, fmt.Sprintf("Could not render heatmap %s: %s", graph.Title, err.Error()))
+				}
+			case "linechart":
+				component, err = linechartPanel(graph, data, utils.FormatTimestamps(data.Times, p.end.Sub(p.start), p.location), p.markers(data.Times), p.cursorPosition(data.Times), visibleSeries(graph, data, p.filter), p.explore || p.fullscreen)
+				if err != nil {
+					component = renderError(graph, fmt.Sprintf("Could not load render linechart %s: %s", graph.Title, err.Error()))
 				}
 			}
+		}
+	}
 
-			cols = append(cols, grid.ColWidthPerc(graph.Width, component))
+	if component == nil {
+		component = renderError(graph, fmt.Sprintf("Invalid graph type %s", graph.Type))
+	}
+
+	return component
+}
+
+// hasAlerts returns true, when the graph has alerts, which are evaluated for the data of the graph.
+func (p panel) hasAlerts() bool {
+	return len(p.graph.Alerts) > 0 && p.dsErr == nil && p.graph.Type != "table" && p.graph.Type != "logs" && p.graph.Type != "alertlist"
+}
+
+// evaluateAlerts evaluates the alerts of the graph for all series of the given data and updates the state of the alerts
+// in the storage.
+func (p panel) evaluateAlerts(data *datasource.Data) {
+	if len(p.graph.Alerts) == 0 {
+		return
+	}
+
+	var results []utils.AlertResult
+
+	for _, alert := range p.graph.Alerts {
+		for _, series := range data.Series {
+			if len(series.Points) == 0 {
+				continue
+			}
+
+			value := getStatValue(alert.GetStat(), series.Points)
+			active, err := alert.Check(value)
+			if err != nil {
+				fLog.Debugf("could not evaluate alert %s: %s", alert.Name, err.Error())
+			}
+
+			results = append(results, utils.AlertResult{Alert: alert, Series: series.Label, Value: value, Active: active})
 		}
+	}
+
+	p.alerts.Update(p.dashboard, p.id, p.graph.Title, results, time.Now())
+}
 
-		rows = append(rows, grid.RowHeightPerc(row.Height, cols...))
+// cursorPosition returns the position of the cursor in the given times. If the cursor is not active for the panel nil
+// is returned.
+func (p panel) cursorPosition(times []time.Time) *cursor {
+	if !p.cursorOn || len(times) == 0 {
+		return nil
 	}
 
-	builder := grid.New()
-	builder.Add(rows...)
-	gridOpts, _ := builder.Build()
-	return gridOpts
+	index := cursorIndex(times, p.cursor)
+
+	return &cursor{
+		index: index,
+		label: times[index].In(p.location).Format("2006-01-02 15:04:05"),
+	}
+}
+
+// cursorIndex returns the index of the first time, which is not before the given time. If all times are before the
+// given time the index of the last time is returned.
+func cursorIndex(times []time.Time, t time.Time) int {
+	index := sort.Search(len(times), func(i int) bool { return !times[i].Before(t) })
+	if index >= len(times) {
+		index = len(times) - 1
+	}
+
+	return index
+}
+
+// markers returns the markers for the annotations of the dashboard, which are in the range of the given times. If the
+// annotations could not be loaded the linechart is rendered without markers.
+func (p panel) markers(times []time.Time) []marker {
+	if len(times) == 0 {
+		return nil
+	}
+
+	events, err := p.annotations.get()
+	if err != nil {
+		fLog.Debugf("could not load annotations: %s", err.Error())
+		return nil
+	}
+
+	format := utils.TimestampFormat(p.end.Sub(p.start))
+
+	var markers []marker
+	for _, event := range events {
+		if event.Time.Before(times[0]) || event.Time.After(times[len(times)-1]) {
+			continue
+		}
+
+		text := event.Name
+		if event.Label != "" {
+			text = fmt.Sprintf("%s %s", event.Name, event.Label)
+		}
+
+		color := cell.ColorYellow
+		if event.Color != "" {
+			color = getColor(event.Color)
+		}
+
+		markers = append(markers, marker{
+			index: sort.Search(len(times), func(i int) bool { return !times[i].Before(event.Time) }),
+			text:  fmt.Sprintf("%s %s", event.Time.In(p.location).Format(format), text),
+			color: color,
+		})
+	}
+
+	return markers
+}
+
+func renderLoading(graph dashboard.Graph, id string, focused bool) grid.Element {
+	txt, _ := text.New()
+	txt.Write("Loading...")
+
+	// The border color is kept, when the placeholder is replaced by the graph, so that the border color of the focused
+	// graph must only be set here and when the focus is moved.
+	borderColor := cell.ColorDefault
+	if focused {
+		borderColor = focusColor
+	}
+
+	return grid.Widget(
+		txt,
+		container.ID(id),
+		container.Border(linestyle.Light),
+		container.BorderColor(borderColor),
+		container.BorderTitle(graph.Title),
+		container.AlignHorizontal(align.HorizontalCenter),
+		container.AlignVertical(align.VerticalMiddle),
+	)
 }
 
 func renderError(graph dashboard.Graph, err string) grid.Element {
@@ -269,14 +980,14 @@ func sparklinePanel(graph dashboard.Graph, data *datasource.Data) (grid.Element,
 	return grid.Widget(s, container.Border(linestyle.Light), container.BorderTitle(graph.Title), container.AlignHorizontal(align.HorizontalCenter), container.AlignVertical(align.VerticalMiddle)), nil
 }
 
-func linechartPanel(graph dashboard.Graph, data *datasource.Data, explore bool) (grid.Element, error) {
+func linechartPanel(graph dashboard.Graph, data *datasource.Data, timestamps map[int]string, markers []marker, cur *cursor, visible []int, largeLegend bool) (grid.Element, error) {
 	lc, err := linechart.New()
 	if err != nil {
 		return nil, err
 	}
 
 	legendOptions := text.WrapAtRunes()
-	if explore {
+	if largeLegend {
 		legendOptions = text.WrapAtWords()
 	}
 
@@ -285,22 +996,63 @@ func linechartPanel(graph dashboard.Graph, data *datasource.Data, explore bool)
 		return nil, err
 	}
 
-	for index, series := range data.Series {
+	if cur != nil {
+		separator := "   "
+		if largeLegend || graph.Options.Legend == "right" {
+			separator = "\n"
+		}
+
+		err = legend.Write(fmt.Sprintf("@ %s%s", cur.label, separator), text.WriteCellOpts(cell.FgColor(cursorColor)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// If the series are stacked, the linechart shows the accumulated values, while the legend shows the values of the
+	// series itself.
+	var visibleSeries []datasource.Series
+	for _, index := range visible {
+		visibleSeries = append(visibleSeries, data.Series[index])
+	}
+	if graph.Options.Stacked {
+		visibleSeries = stackSeries(visibleSeries)
+	}
+
+	// The colors of the series are based on their index in the returned data and not on their index in the visible
+	// series, so that the color of a series doesn't change when other series are hidden.
+	for position, index := range visible {
+		series := data.Series[index]
+
 		var stats []string
 		for _, stat := range graph.Options.Stats {
 			stats = append(stats, fmt.Sprintf("%s: %s", stat, strconv.FormatFloat(getStatValue(stat, series.Points), 'f', graph.Options.Decimals, 64)))
 		}
 
+		// The legend shows the value at the cursor instead of the current value, when the cursor is active.
+		value := getStatValue("current", series.Points)
+		if cur != nil {
+			value = math.NaN()
+			if cur.index < len(series.Points) {
+				value = series.Points[cur.index]
+			}
+		}
+
 		var statsLegend string
 		if len(stats) > 0 {
-			statsLegend = fmt.Sprintf("%s %s (%s)", strconv.FormatFloat(getStatValue("current", series.Points), 'f', graph.Options.Decimals, 64), graph.Options.Unit, strings.Join(stats, ", "))
+			statsLegend = fmt.Sprintf("%s %s (%s)", strconv.FormatFloat(value, 'f', graph.Options.Decimals, 64), graph.Options.Unit, strings.Join(stats, ", "))
 		} else {
-			statsLegend = fmt.Sprintf("%s %s", strconv.FormatFloat(getStatValue("current", series.Points), 'f', graph.Options.Decimals, 64), graph.Options.Unit)
+			statsLegend = fmt.Sprintf("%s %s", strconv.FormatFloat(value, 'f', graph.Options.Decimals, 64), graph.Options.Unit)
 		}
 
+		// Series for a shifted time range are rendered with a dimmed color, so that they can be distinguished from the
+		// series of the current time range.
 		color := randomColor(index)
+		if series.TimeShift != 0 {
+			color = dimColor(color)
+		}
+
 		if graph.Options.Legend == "bottom" {
-			if explore {
+			if largeLegend {
 				err = legend.Write(fmt.Sprintf("%s: %s\n", series.Label, statsLegend), text.WriteCellOpts(cell.FgColor(color)))
 				if err != nil {
 					return nil, err
@@ -319,13 +1071,58 @@ func linechartPanel(graph dashboard.Graph, data *datasource.Data, explore bool)
 			}
 		}
 
-		if index == 0 {
-			err = lc.Series(series.Label, series.Points, linechart.SeriesCellOpts(cell.FgColor(color)), linechart.SeriesXLabels(data.Timestamps))
+		if position == 0 {
+			err = lc.Series(series.Label, visibleSeries[position].Points, linechart.SeriesCellOpts(cell.FgColor(color)), linechart.SeriesXLabels(timestamps))
 			if err != nil {
 				return nil, err
 			}
 		} else {
-			err = lc.Series(series.Label, series.Points, linechart.SeriesCellOpts(cell.FgColor(color)))
+			err = lc.Series(series.Label, visibleSeries[position].Points, linechart.SeriesCellOpts(cell.FgColor(color)))
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if hidden := len(data.Series) - len(visible); hidden > 0 && graph.Options.Legend != "" {
+		separator := "   "
+		if largeLegend || graph.Options.Legend == "right" {
+			separator = "\n"
+		}
+
+		err = legend.Write(fmt.Sprintf("(%d hidden)%s", hidden, separator), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(244))))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The linechart doesn't support vertical lines, so that each marker is drawn as a series, which goes from the
+	// minimum to the maximum value of the graph at the time of the event. All other points of the series are NaN and
+	// are not drawn.
+	min, max := valueRange(visibleSeries)
+	for index, m := range markers {
+		points := markerPoints(len(data.Times), m.index, min, max)
+		if points != nil {
+			err = lc.Series(fmt.Sprintf("annotation-%d", index), points, linechart.SeriesCellOpts(cell.FgColor(m.color)))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if graph.Options.Legend == "bottom" && !largeLegend {
+			err = legend.Write(fmt.Sprintf("| %s   ", m.text), text.WriteCellOpts(cell.FgColor(m.color)))
+		} else if graph.Options.Legend == "bottom" || graph.Options.Legend == "right" {
+			err = legend.Write(fmt.Sprintf("| %s\n", m.text), text.WriteCellOpts(cell.FgColor(m.color)))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// The cursor is drawn like a marker for an annotation.
+	if cur != nil {
+		if points := markerPoints(len(data.Times), cur.index, min, max); points != nil {
+			err = lc.Series("cursor", points, linechart.SeriesCellOpts(cell.FgColor(cursorColor)))
 			if err != nil {
 				return nil, err
 			}
@@ -335,10 +1132,11 @@ func linechartPanel(graph dashboard.Graph, data *datasource.Data, explore bool)
 	// Render linechart and legend
 	// See: https://github.com/slok/grafterm/blob/master/internal/view/render/termdash/graph.go
 	//
-	// If the linechart is used for the explore mode we increase the space for rendering the labels.
+	// If the linechart is used for the explore mode or shown in full screen we increase the space for rendering the
+	// labels.
 	graphVerticalPerc := 90
 	legendVerticalPerc := 4
-	if explore {
+	if largeLegend {
 		graphVerticalPerc = 50
 		legendVerticalPerc = 44
 	}
@@ -363,6 +1161,243 @@ func linechartPanel(graph dashboard.Graph, data *datasource.Data, explore bool)
 	return element, nil
 }
 
+// stackSeries returns the given series with accumulated values, so that each series is drawn on top of the previous
+// series. Missing values are not added, but they are kept as missing values in the stacked series. Series for a
+// shifted time range are not stacked, because they are compared against the stacked series of the current time range.
+func stackSeries(series []datasource.Series) []datasource.Series {
+	var stacked []datasource.Series
+	var total []float64
+
+	for _, s := range series {
+		if s.TimeShift != 0 {
+			stacked = append(stacked, s)
+			continue
+		}
+
+		points := make([]float64, len(s.Points))
+		for index, point := range s.Points {
+			if index >= len(total) {
+				total = append(total, 0)
+			}
+
+			if math.IsNaN(point) {
+				points[index] = math.NaN()
+				continue
+			}
+
+			total[index] = total[index] + point
+			points[index] = total[index]
+		}
+
+		s.Points = points
+		stacked = append(stacked, s)
+	}
+
+	return stacked
+}
+
+func barchartPanel(graph dashboard.Graph, data *datasource.Data, visible []int) (grid.Element, error) {
+	if len(graph.Options.Stats) == 0 {
+		graph.Options.Stats = []string{"current"}
+	}
+
+	// The barchart only supports positive integer values, so that the values are scaled by the number of decimals.
+	// Negative and missing values are shown as empty bars.
+	scale := math.Pow(10, float64(graph.Options.Decimals))
+
+	var values []int
+	var labels []string
+	var colors []cell.Color
+	var legendValues []string
+	max := 1
+
+	for _, index := range visible {
+		series := data.Series[index]
+
+		value := math.NaN()
+		if len(series.Points) > 0 {
+			value = getStatValue(graph.Options.Stats[0], series.Points)
+		}
+
+		scaled := 0
+		if !math.IsNaN(value) && value > 0 {
+			scaled = int(math.Round(value * scale))
+		}
+		if scaled > max {
+			max = scaled
+		}
+
+		color := randomColor(index)
+		if series.TimeShift != 0 {
+			color = dimColor(color)
+		}
+
+		values = append(values, scaled)
+		labels = append(labels, series.Label)
+		colors = append(colors, color)
+		legendValues = append(legendValues, fmt.Sprintf("%s: %s %s", series.Label, strconv.FormatFloat(value, 'f', graph.Options.Decimals, 64), graph.Options.Unit))
+	}
+
+	bc, err := barchart.New(barchart.BarColors(colors), barchart.LabelColors(colors), barchart.Labels(labels))
+	if err != nil {
+		return nil, err
+	}
+
+	err = bc.Values(values, max)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []container.Option{container.Border(linestyle.Light), container.BorderTitle(graph.Title), container.AlignHorizontal(align.HorizontalCenter), container.AlignVertical(align.VerticalMiddle)}
+
+	if graph.Options.Legend != "bottom" && graph.Options.Legend != "right" {
+		return grid.RowHeightPercWithOpts(99, opts, grid.ColWidthPerc(99, grid.Widget(bc))), nil
+	}
+
+	legend, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		return nil, err
+	}
+
+	for index, value := range legendValues {
+		separator := "   "
+		if graph.Options.Legend == "right" {
+			separator = "\n"
+		}
+
+		err = legend.Write(value+separator, text.WriteCellOpts(cell.FgColor(colors[index])))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var elements []grid.Element
+	if graph.Options.Legend == "bottom" {
+		legendElement := grid.RowHeightPercWithOpts(99, []container.Option{container.PaddingTopPercent(10)}, grid.Widget(legend))
+		elements = []grid.Element{grid.RowHeightPerc(90, grid.Widget(bc)), grid.RowHeightPerc(4, legendElement)}
+	} else {
+		legendElement := grid.ColWidthPercWithOpts(99, []container.Option{container.PaddingLeftPercent(10)}, grid.Widget(legend))
+		elements = []grid.Element{grid.ColWidthPerc(80, grid.Widget(bc)), grid.ColWidthPerc(19, legendElement)}
+	}
+
+	return grid.RowHeightPercWithOpts(99, opts, elements...), nil
+}
+
+// visibleSeries returns the indices of the series, which should be shown in a linechart or barchart. The series are
+// filtered by the regular expression and the top n option of the graph, which can be overwritten via the given filter.
+// Afterwards the series which were hidden or isolated via the filter are removed.
+func visibleSeries(graph dashboard.Graph, data *datasource.Data, filter utils.SeriesFilter) []int {
+	pattern := graph.Options.Filter
+	if filter.Regexp != "" {
+		pattern = filter.Regexp
+	}
+
+	var re *regexp.Regexp
+	if pattern != "" {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			fLog.Debugf("invalid filter %s for %s: %s", pattern, graph.Title, err.Error())
+		}
+	}
+
+	var indices []int
+	for index, series := range data.Series {
+		if re == nil || re.MatchString(series.Label) {
+			indices = append(indices, index)
+		}
+	}
+
+	topN := graph.Options.TopN
+	if filter.TopN > 0 {
+		topN = filter.TopN
+	}
+
+	if topN > 0 && len(indices) > topN {
+		stat := graph.Options.TopNStat
+		if filter.Stat != "" {
+			stat = filter.Stat
+		}
+		if stat == "" {
+			stat = "max"
+		}
+
+		values := make(map[int]float64)
+		for _, index := range indices {
+			values[index] = math.Inf(-1)
+			if points := data.Series[index].Points; len(points) > 0 {
+				if value := getStatValue(stat, points); !math.IsNaN(value) {
+					values[index] = value
+				}
+			}
+		}
+
+		sort.SliceStable(indices, func(i, j int) bool {
+			return values[indices[i]] > values[indices[j]]
+		})
+		indices = indices[:topN]
+		sort.Ints(indices)
+	}
+
+	var visible []int
+	for _, index := range indices {
+		label := data.Series[index].Label
+		if filter.Hidden[label] || (filter.Isolated != "" && filter.Isolated != label) {
+			continue
+		}
+
+		visible = append(visible, index)
+	}
+
+	return visible
+}
+
+// valueRange returns the minimum and maximum value of all series. If there are no values NaN is returned.
+func valueRange(series []datasource.Series) (float64, float64) {
+	min, max := math.NaN(), math.NaN()
+
+	for _, s := range series {
+		for _, point := range s.Points {
+			if math.IsNaN(point) {
+				continue
+			}
+
+			if math.IsNaN(min) || point < min {
+				min = point
+			}
+			if math.IsNaN(max) || point > max {
+				max = point
+			}
+		}
+	}
+
+	return min, max
+}
+
+// markerPoints returns the points for a marker at the given index. The marker is drawn as a line from the minimum value
+// at the index to the maximum value at the next index, because the linechart can't draw two values for the same index.
+// If there is no range between the minimum and maximum value nil is returned.
+func markerPoints(length, index int, min, max float64) []float64 {
+	if length < 2 || math.IsNaN(min) || math.IsNaN(max) || min == max {
+		return nil
+	}
+
+	points := make([]float64, length)
+	for i := range points {
+		points[i] = math.NaN()
+	}
+
+	if index+1 < length {
+		points[index] = min
+		points[index+1] = max
+	} else {
+		points[index-1] = max
+		points[index] = min
+	}
+
+	return points
+}
+
 func tablePanel(graph dashboard.Graph, data *datasource.TableData) (grid.Element, error) {
 	tableString := &strings.Builder{}
 	table := tablewriter.NewWriter(tableString)
@@ -401,6 +1436,130 @@ func tablePanel(graph dashboard.Graph, data *datasource.TableData) (grid.Element
 	return grid.Widget(txt, container.Border(linestyle.Light), container.BorderTitle(graph.Title), container.AlignHorizontal(align.HorizontalCenter), container.AlignVertical(align.VerticalMiddle)), nil
 }
 
+func logsPanel(graph dashboard.Graph, lines []datasource.LogLine, location *time.Location) (grid.Element, error) {
+	// The log lines are sorted from the oldest to the newest one. We are using the RollContent option for the text
+	// widget, so that always the newest log lines are shown and it is possible to scroll back to older ones.
+	txt, err := text.New(text.WrapAtRunes(), text.RollContent())
+	if err != nil {
+		return nil, err
+	}
+
+	for index, line := range lines {
+		err = txt.Write(line.Timestamp.In(location).Format("01/02 15:04:05")+" ", text.WriteCellOpts(cell.FgColor(cell.ColorYellow)))
+		if err != nil {
+			return nil, err
+		}
+
+		if line.Label != "" {
+			err = txt.Write(line.Label+" ", text.WriteCellOpts(cell.FgColor(cell.ColorCyan)))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		content := sanitizeText(line.Line)
+		if index < len(lines)-1 {
+			content = content + "\n"
+		}
+
+		err = txt.Write(content)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return grid.Widget(txt, container.Border(linestyle.Light), container.BorderTitle(graph.Title)), nil
+}
+
+// alertlistPanel renders the given alerts, beginning with the newest one. For each alert the time since it is firing,
+// the name, the severity and all other labels are shown.
+func alertlistPanel(graph dashboard.Graph, alerts []datasource.Alert, location *time.Location) (grid.Element, error) {
+	txt, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		return nil, err
+	}
+
+	if len(alerts) == 0 {
+		err = txt.Write("No alerts are firing", text.WriteCellOpts(cell.FgColor(cell.ColorGreen)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for index, alert := range alerts {
+		err = txt.Write(alert.StartsAt.In(location).Format("01/02 15:04:05")+" ", text.WriteCellOpts(cell.FgColor(cell.ColorYellow)))
+		if err != nil {
+			return nil, err
+		}
+
+		err = txt.Write(sanitizeText(alert.Name)+" ", text.WriteCellOpts(cell.FgColor(severityColor(alert.Severity))))
+		if err != nil {
+			return nil, err
+		}
+
+		if alert.Severity != "" {
+			err = txt.Write(fmt.Sprintf("[%s] ", sanitizeText(alert.Severity)), text.WriteCellOpts(cell.FgColor(severityColor(alert.Severity))))
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		var labels []string
+		for key, value := range alert.Labels {
+			if key != "alertname" && key != "severity" {
+				labels = append(labels, fmt.Sprintf("%s=%s", key, value))
+			}
+		}
+		sort.Strings(labels)
+
+		content := sanitizeText(strings.Join(labels, ", "))
+		if index < len(alerts)-1 {
+			content = content + "\n"
+		}
+
+		err = txt.Write(content, text.WriteCellOpts(cell.FgColor(cell.ColorCyan)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return grid.Widget(txt, container.Border(linestyle.Light), container.BorderTitle(graph.Title)), nil
+}
+
+// severityColor returns the color for the severity of an alert.
+func severityColor(severity string) cell.Color {
+	switch strings.ToLower(severity) {
+	case "critical", "error", "page":
+		return cell.ColorRed
+	case "warning":
+		return cell.ColorYellow
+	case "info":
+		return cell.ColorBlue
+	default:
+		return cell.ColorWhite
+	}
+}
+
+// sanitizeText replaces all characters in the given text, which can not be rendered by the text widget. Tabs and
+// newlines are replaced by spaces, all other control characters are removed.
+func sanitizeText(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r == '\t' || r == '\n' {
+			return ' '
+		}
+		if r != ' ' && (unicode.IsControl(r) || unicode.IsSpace(r)) {
+			return -1
+		}
+		return r
+	}, strings.TrimRight(value, "\r\n"))
+
+	if value == "" {
+		return " "
+	}
+
+	return value
+}
+
 func formateInterface(value interface{}, decimals int) string {
 	switch i := value.(type) {
 	case float64:
@@ -444,6 +1603,26 @@ func randomColor(index int) cell.Color {
 	return cell.ColorNumber(rand.Intn(255-0) + 0)
 }
 
+// dimColor returns a darker variant of the given color. For colors without a darker variant gray is returned.
+func dimColor(color cell.Color) cell.Color {
+	switch color {
+	case cell.ColorBlue:
+		return cell.ColorNumber(18)
+	case cell.ColorCyan:
+		return cell.ColorNumber(30)
+	case cell.ColorGreen:
+		return cell.ColorNumber(22)
+	case cell.ColorMagenta:
+		return cell.ColorNumber(90)
+	case cell.ColorRed:
+		return cell.ColorNumber(88)
+	case cell.ColorYellow:
+		return cell.ColorNumber(100)
+	default:
+		return cell.ColorNumber(244)
+	}
+}
+
 func getStatValue(stat string, data []float64) float64 {
 	switch stat {
 	case "current":
@@ -479,7 +1658,7 @@ func getStatValue(stat string, data []float64) float64 {
 		}
 		return total
 	case "diff":
-		return data[0] - data[len(data)]
+		return data[0] - data[len(data)-1]
 	case "range":
 		min := data[0]
 		max := data[0]
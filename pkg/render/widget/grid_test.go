@@ -0,0 +1,147 @@
+package widget
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/dashboard"
+	"github.com/ricoberger/dash/pkg/datasource"
+	"github.com/ricoberger/dash/pkg/render/utils"
+)
+
+// testClient is a datasource, which returns one point per step between the start and end time and records the
+// requested time ranges. The value of a point is its unix timestamp. If aligned is true, the step is reported as
+// aligned step in the trace of a request.
+type testClient struct {
+	datasource.File
+	mu       sync.Mutex
+	step     time.Duration
+	aligned  bool
+	requests [][2]time.Time
+}
+
+func (c *testClient) GetData(ctx context.Context, queries, labels []string, start, end time.Time) (*datasource.Data, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, [2]time.Time{start, end})
+	c.mu.Unlock()
+
+	if trace, ok := datasource.TraceFromContext(ctx); ok && c.step > 0 {
+		trace.Step = c.step
+		trace.Aligned = c.aligned
+	}
+
+	step := c.step
+	if step == 0 {
+		step = time.Minute
+	}
+
+	data := &datasource.Data{Series: []datasource.Series{{Label: "series"}}}
+	for t := start.Truncate(step); !t.After(end); t = t.Add(step) {
+		data.Times = append(data.Times, t)
+		data.Series[0].Points = append(data.Series[0].Points, float64(t.Unix()))
+	}
+
+	return data, nil
+}
+
+func testPanel(ds datasource.Client, alerts *utils.Alerts, start, end time.Time) panel {
+	return panel{
+		ctx:       context.Background(),
+		id:        panelID(0, 0),
+		key:       "key",
+		graph:     dashboard.Graph{Title: "Graph", Type: "linechart", Queries: []dashboard.Query{{Query: "up"}}, Alerts: []dashboard.Alert{{Name: "Always", Operator: ">", Value: 0, For: "1h"}}},
+		ds:        ds,
+		start:     start,
+		end:       end,
+		alerts:    alerts,
+		dashboard: "dashboard",
+	}
+}
+
+func TestGridGetDataEvaluatesAlertsOnce(t *testing.T) {
+	alerts := utils.NewAlerts("", false)
+
+	var changes int
+	alerts.OnChange(func() {
+		changes++
+	})
+
+	g := &Grid{data: make(map[string]panelData)}
+	start := time.Unix(1577836800, 0)
+	p := testPanel(&testClient{step: time.Minute, aligned: true}, alerts, start, start.Add(time.Hour))
+
+	if _, err := g.getData(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, pending := alerts.Count(); pending != 1 || changes != 1 {
+		t.Fatalf("expected 1 pending alert and 1 change, got %d pending alerts and %d changes", pending, changes)
+	}
+
+	// Rendering the graph again with the already loaded data must not evaluate the alerts again.
+	alerts.Update("dashboard", p.id, p.graph.Title, nil, time.Now())
+	p.cached = true
+	if _, err := g.getData(p); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, pending := alerts.Count(); pending != 0 {
+		t.Errorf("expected no evaluation for cached data, got %d pending alerts", pending)
+	}
+}
+
+func TestGridGetDataIncremental(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		aligned     bool
+		incremental bool
+	}{
+		{name: "aligned", aligned: true, incremental: true},
+		{name: "not aligned", aligned: false, incremental: false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			client := &testClient{step: time.Minute, aligned: tc.aligned}
+			g := &Grid{data: make(map[string]panelData)}
+			alerts := utils.NewAlerts("", false)
+
+			start := time.Unix(1577836800, 0)
+			end := start.Add(10 * time.Minute)
+
+			if _, err := g.getData(testPanel(client, alerts, start, end)); err != nil {
+				t.Fatal(err)
+			}
+
+			// The time range is moved by five minutes, so that only the points of the last five minutes must be loaded
+			// for aligned datasources.
+			data, err := g.getData(testPanel(client, alerts, start.Add(5*time.Minute), end.Add(5*time.Minute)))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(client.requests) != 2 {
+				t.Fatalf("expected 2 requests, got %d", len(client.requests))
+			}
+
+			expectedStart := start.Add(5 * time.Minute)
+			if tc.incremental {
+				expectedStart = end
+			}
+
+			if !client.requests[1][0].Equal(expectedStart) {
+				t.Errorf("expected the second request to start at %s, got %s", expectedStart, client.requests[1][0])
+			}
+
+			if len(data.Times) != 11 || !data.Times[0].Equal(start.Add(5*time.Minute)) || !data.Times[10].Equal(end.Add(5*time.Minute)) {
+				t.Fatalf("unexpected times %v", data.Times)
+			}
+
+			for index, point := range data.Series[0].Points {
+				if point != float64(data.Times[index].Unix()) {
+					t.Errorf("unexpected point %v at %s", point, data.Times[index])
+				}
+			}
+		})
+	}
+}
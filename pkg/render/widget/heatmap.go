@@ -0,0 +1,274 @@
+package widget
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/dashboard"
+	"github.com/ricoberger/dash/pkg/datasource"
+	"github.com/ricoberger/dash/pkg/render/utils"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/container/grid"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+// heatmapColors are the colors of the cells of a heatmap, from a low to a high density.
+var heatmapColors = []cell.Color{
+	cell.ColorNumber(17), cell.ColorNumber(18), cell.ColorNumber(19), cell.ColorNumber(20), cell.ColorNumber(26),
+	cell.ColorNumber(32), cell.ColorNumber(38), cell.ColorNumber(44), cell.ColorNumber(43), cell.ColorNumber(42),
+	cell.ColorNumber(41), cell.ColorNumber(76), cell.ColorNumber(112), cell.ColorNumber(148), cell.ColorNumber(184),
+	cell.ColorNumber(220), cell.ColorNumber(214), cell.ColorNumber(208), cell.ColorNumber(202), cell.ColorNumber(196),
+}
+
+// bucket is a single bucket of a histogram. The points of the bucket are not cumulative, so that they only contain the
+// observations between the upper bound of the previous bucket and the upper bound of the bucket.
+type bucket struct {
+	le     float64
+	label  string
+	points []float64
+}
+
+// getBuckets returns the buckets of a Prometheus histogram from the given data. The series must have a "le" label.
+// Series with the same "le" label are summed up, the buckets are sorted by their upper bound and the cumulative
+// values of the buckets are converted to the number of observations in each bucket.
+func getBuckets(data *datasource.Data) ([]bucket, error) {
+	buckets := make(map[string]*bucket)
+
+	for _, series := range data.Series {
+		label, ok := series.Labels["le"]
+		if !ok {
+			continue
+		}
+
+		le, err := strconv.ParseFloat(label, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid le label %s: %s", label, err.Error())
+		}
+
+		b, ok := buckets[label]
+		if !ok {
+			b = &bucket{le: le, label: label, points: make([]float64, len(data.Times))}
+			buckets[label] = b
+		}
+
+		for index, point := range series.Points {
+			if index < len(b.points) && !math.IsNaN(point) {
+				b.points[index] = b.points[index] + point
+			}
+		}
+	}
+
+	if len(buckets) == 0 {
+		return nil, fmt.Errorf("no series with a le label")
+	}
+
+	var sorted []bucket
+	for _, b := range buckets {
+		sorted = append(sorted, *b)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].le < sorted[j].le
+	})
+
+	// The buckets are de-accumulated from the highest to the lowest bucket, so that the cumulative values of the
+	// previous bucket are still available. Negative values can occur, when the series are not scraped at the same time
+	// and are set to zero.
+	for i := len(sorted) - 1; i > 0; i-- {
+		points := make([]float64, len(sorted[i].points))
+		for index := range sorted[i].points {
+			points[index] = math.Max(sorted[i].points[index]-sorted[i-1].points[index], 0)
+		}
+		sorted[i].points = points
+	}
+
+	return sorted, nil
+}
+
+// mergeBuckets reduces the number of buckets to the given number of rows, by adding up the points of all buckets which
+// are merged into a row. A merged bucket has the upper bound of the highest bucket it contains.
+func mergeBuckets(buckets []bucket, rows int) []bucket {
+	if len(buckets) <= rows {
+		return buckets
+	}
+
+	merged := make([]bucket, rows)
+	for row := range merged {
+		start := row * len(buckets) / rows
+		end := (row + 1) * len(buckets) / rows
+
+		points := make([]float64, len(buckets[start].points))
+		for _, b := range buckets[start:end] {
+			for index, point := range b.points {
+				points[index] = points[index] + point
+			}
+		}
+
+		merged[row] = bucket{le: buckets[end-1].le, label: buckets[end-1].label, points: points}
+	}
+
+	return merged
+}
+
+// mergePoints reduces the number of points to the given number of columns, by using the average of all points which
+// are merged into a column.
+func mergePoints(points []float64, columns int) []float64 {
+	if len(points) <= columns {
+		return points
+	}
+
+	merged := make([]float64, columns)
+	for column := range merged {
+		start := column * len(points) / columns
+		end := (column + 1) * len(points) / columns
+
+		var total float64
+		for _, point := range points[start:end] {
+			total = total + point
+		}
+		merged[column] = total / float64(end-start)
+	}
+
+	return merged
+}
+
+// span returns the number of cells of the item with the given index, when the given number of cells is distributed
+// over the given number of items. The cells are distributed evenly, so that all cells are used.
+func span(index, items, cells int) int {
+	return (index+1)*cells/items - index*cells/items
+}
+
+// heatmapColor returns the color for a cell of a heatmap with the given value. Cells without observations are not
+// colored.
+func heatmapColor(value, max float64) (cell.Color, bool) {
+	if value <= 0 || max <= 0 {
+		return cell.ColorDefault, false
+	}
+
+	index := int(math.Ceil(value/max*float64(len(heatmapColors)))) - 1
+	if index < 0 {
+		index = 0
+	} else if index >= len(heatmapColors) {
+		index = len(heatmapColors) - 1
+	}
+
+	return heatmapColors[index], true
+}
+
+// heatmapPanel renders the buckets of a Prometheus histogram as heatmap. The heatmap is drawn into a single text
+// widget, where each cell of the heatmap is a number of spaces with the background color for the number of
+// observations. The given size is the number of cells within the border of the panel. The buckets and points are
+// spread over all available cells and are only merged when there are more buckets than lines or more points than
+// columns. The highest bucket is rendered at the top of the heatmap and the last line contains the legend.
+func heatmapPanel(graph dashboard.Graph, data *datasource.Data, location *time.Location, size image.Point) (grid.Element, error) {
+	buckets, err := getBuckets(data)
+	if err != nil {
+		return nil, err
+	}
+
+	labelWidth := 0
+	for _, b := range buckets {
+		if len(b.label) > labelWidth {
+			labelWidth = len(b.label)
+		}
+	}
+	labelWidth = labelWidth + 1
+
+	rows := size.Y - 1
+	columns := size.X - labelWidth
+	if rows < 1 || columns < 1 {
+		return nil, fmt.Errorf("panel is too small: %dx%d cells", size.X, size.Y)
+	}
+
+	buckets = mergeBuckets(buckets, rows)
+
+	var max float64
+	for index := range buckets {
+		buckets[index].points = mergePoints(buckets[index].points, columns)
+		for _, point := range buckets[index].points {
+			max = math.Max(max, point)
+		}
+	}
+
+	heatmap, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(buckets) - 1; i >= 0; i-- {
+		b := buckets[i]
+
+		// The label is only written on the first line of a bucket, when a bucket uses more than one line.
+		lines := span(len(buckets)-1-i, len(buckets), rows)
+		for line := 0; line < lines; line++ {
+			label := ""
+			if line == 0 {
+				label = b.label
+			}
+
+			err = heatmap.Write(fmt.Sprintf("%-*s", labelWidth, label))
+			if err != nil {
+				return nil, err
+			}
+
+			for index, point := range b.points {
+				cells := strings.Repeat(" ", span(index, len(b.points), columns))
+
+				if color, ok := heatmapColor(point, max); ok {
+					err = heatmap.Write(cells, text.WriteCellOpts(cell.BgColor(color)))
+				} else {
+					err = heatmap.Write(cells)
+				}
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			err = heatmap.Write("\n")
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// The legend contains the start and end time of the heatmap and the scale of the colors, from the lowest to the
+	// highest number of observations in a cell.
+	if len(data.Times) > 0 {
+		start := data.Times[0].In(location)
+		end := data.Times[len(data.Times)-1].In(location)
+		format := utils.TimestampFormat(end.Sub(start))
+
+		err = heatmap.Write(fmt.Sprintf("%s - %s   ", start.Format(format), end.Format(format)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = heatmap.Write("0 ")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, color := range heatmapColors {
+		err = heatmap.Write(" ", text.WriteCellOpts(cell.BgColor(color)))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = heatmap.Write(fmt.Sprintf(" %s %s", strconv.FormatFloat(max, 'f', graph.Options.Decimals, 64), graph.Options.Unit))
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []container.Option{container.Border(linestyle.Light), container.BorderTitle(graph.Title)}
+	return grid.RowHeightPercWithOpts(99, opts, grid.Widget(heatmap)), nil
+}
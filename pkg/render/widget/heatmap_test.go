@@ -0,0 +1,85 @@
+package widget
+
+import (
+	"image"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ricoberger/dash/pkg/dashboard"
+	"github.com/ricoberger/dash/pkg/datasource"
+)
+
+func testHistogram() *datasource.Data {
+	start := time.Unix(1577836800, 0)
+
+	return &datasource.Data{
+		Times: []time.Time{start, start.Add(time.Minute), start.Add(2 * time.Minute)},
+		Series: []datasource.Series{
+			{Labels: map[string]string{"le": "+Inf"}, Points: []float64{10, 12, 20}},
+			{Labels: map[string]string{"le": "0.1"}, Points: []float64{2, 4, 5}},
+			{Labels: map[string]string{"le": "0.5"}, Points: []float64{8, 8, 15}},
+		},
+	}
+}
+
+func TestGetBuckets(t *testing.T) {
+	buckets, err := getBuckets(testHistogram())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []bucket{
+		{le: 0.1, label: "0.1", points: []float64{2, 4, 5}},
+		{le: 0.5, label: "0.5", points: []float64{6, 4, 10}},
+		{le: math.Inf(1), label: "+Inf", points: []float64{2, 4, 5}},
+	}
+
+	if !reflect.DeepEqual(buckets, expected) {
+		t.Errorf("unexpected buckets %v", buckets)
+	}
+}
+
+func TestMergeBuckets(t *testing.T) {
+	buckets, err := getBuckets(testHistogram())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if merged := mergeBuckets(buckets, 3); !reflect.DeepEqual(merged, buckets) {
+		t.Errorf("buckets must not be merged when there are enough rows, got %v", merged)
+	}
+
+	merged := mergeBuckets(buckets, 2)
+	if len(merged) != 2 || merged[0].label != "0.1" || merged[1].label != "+Inf" || !reflect.DeepEqual(merged[1].points, []float64{8, 8, 15}) {
+		t.Errorf("unexpected merged buckets %v", merged)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	var total int
+	for index := 0; index < 7; index++ {
+		cells := span(index, 7, 50)
+		if cells != 7 && cells != 8 {
+			t.Errorf("unexpected span %d for item %d", cells, index)
+		}
+		total = total + cells
+	}
+
+	if total != 50 {
+		t.Errorf("expected all 50 cells to be used, got %d", total)
+	}
+}
+
+func TestHeatmapPanelSize(t *testing.T) {
+	graph := dashboard.Graph{Title: "Request Duration", Type: "heatmap"}
+
+	if _, err := heatmapPanel(graph, testHistogram(), time.UTC, image.Point{X: 60, Y: 15}); err != nil {
+		t.Errorf("unexpected error %s", err.Error())
+	}
+
+	if _, err := heatmapPanel(graph, testHistogram(), time.UTC, image.Point{X: 4, Y: 1}); err == nil {
+		t.Error("expected an error for a panel without space for the heatmap")
+	}
+}
@@ -1,10 +1,19 @@
 package widget
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/ricoberger/dash/pkg/dashboard"
 	"github.com/ricoberger/dash/pkg/render/utils"
 
 	"github.com/mum4k/termdash/widgets/text"
@@ -19,10 +28,18 @@ const (
 	ModalTypeInterval   ModalType = "Interval"
 	ModalTypeRefresh    ModalType = "Refresh"
 	ModalTypeExplore    ModalType = "Explore"
+	ModalTypeAlerts     ModalType = "Alerts"
+	ModalTypeInspect    ModalType = "Inspect"
+	ModalTypeSeries     ModalType = "Series"
 )
 
+// errInspectionShown is returned by Select, when the inspection of a graph is shown or exported, so that the modal is
+// not closed.
+var errInspectionShown = errors.New("inspection is shown")
+
 var intervals = []string{"5m", "15m", "30m", "1h", "3h", "6h", "12h", "24h", "2d", "7d", "30d"}
 var refreshs = []string{"5s", "10s", "30s", "1m", "5m", "15m", "30m", "1h", "2h", "1d"}
+var seriesStats = []string{"current", "first", "min", "max", "avg", "total", "diff", "range"}
 
 type Modal struct {
 	*text.Text
@@ -31,11 +48,17 @@ type Modal struct {
 	options *ModalOptions
 	rows    []string
 	index   string
+	err     error
+
+	inspection string
+	message    string
 }
 
 type ModalOptions struct {
 	Type          ModalType
 	VariableIndex int
+	GraphID       string
+	Series        []string
 }
 
 func NewModal(storage *utils.Storage) (*Modal, error) {
@@ -51,6 +74,9 @@ func NewModal(storage *utils.Storage) (*Modal, error) {
 		nil,
 		nil,
 		"",
+		nil,
+		"",
+		"",
 	}, nil
 }
 
@@ -74,7 +100,7 @@ func (m *Modal) show(updateRows bool) bool {
 			}
 
 			variable := m.storage.Dashboard().Variables[m.options.VariableIndex]
-			values, err := variable.GetValues(m.storage.Datasource(), m.storage.VariableValues, m.storage.Interval.Start, m.storage.Interval.End)
+			values, err := variable.GetValues(m.storage.Context(), m.storage.Datasource(), m.storage.VariableValues, m.storage.Interval.Start, m.storage.Interval.End)
 			if err != nil {
 				return false
 			}
@@ -92,6 +118,30 @@ func (m *Modal) show(updateRows bool) bool {
 			}
 		} else if m.options.Type == ModalTypeExplore {
 			m.rows = m.storage.GetSuggestions(m.index)
+		} else if m.options.Type == ModalTypeInspect {
+			for index, graph := range m.graphs() {
+				m.rows = append(m.rows, fmt.Sprintf("%3d: %s (%s)", index, graph.Title, graph.Type))
+			}
+		} else if m.options.Type == ModalTypeSeries {
+			filter := m.storage.SeriesFilter(m.options.GraphID)
+			for index, label := range m.options.Series {
+				visible := " "
+				if !filter.Hidden[label] && (filter.Isolated == "" || filter.Isolated == label) {
+					visible = "x"
+				}
+
+				m.rows = append(m.rows, fmt.Sprintf("%3d: [%s] %s", index, visible, label))
+			}
+		} else if m.options.Type == ModalTypeAlerts {
+			location := m.storage.Location()
+			for _, alert := range m.storage.Alerts.List() {
+				state := fmt.Sprintf("[%s]", alert.State)
+				if alert.Alert.Severity != "" {
+					state = fmt.Sprintf("%s %s", state, alert.Alert.Severity)
+				}
+
+				m.rows = append(m.rows, fmt.Sprintf("%s %s / %s (%s): %s %s %s %s since %s", state, alert.Graph, alert.Alert.Name, alert.Series, alert.Alert.GetStat(), strconv.FormatFloat(alert.Value, 'f', -1, 64), alert.Alert.Operator, strconv.FormatFloat(alert.Alert.Value, 'f', -1, 64), alert.ActiveAt.In(location).Format("01/02 15:04:05")))
+			}
 		} else {
 			return false
 		}
@@ -102,6 +152,45 @@ func (m *Modal) show(updateRows bool) bool {
 		if err != nil {
 			return false
 		}
+	} else if m.options.Type == ModalTypeInspect && m.inspection != "" {
+		help := "Press Enter to export the inspection to a file"
+		if m.message != "" {
+			help = m.message
+		}
+		err := m.Write(fmt.Sprintf("%s\n\n%s", help, m.inspection))
+		if err != nil {
+			return false
+		}
+	} else if m.options.Type == ModalTypeAlerts {
+		if len(m.rows) == 0 {
+			m.rows = []string{"No alerts are pending or firing"}
+		}
+		err := m.Write(fmt.Sprintf("Alerts of the dashboard %s:\n\n%s", m.storage.Dashboard().Name, strings.Join(m.rows, "\n")))
+		if err != nil {
+			return false
+		}
+	} else if m.options.Type == ModalTypeSeries {
+		help := "Enter an index to hide or show a series, \"i<index>\" to isolate a series, \"/<regexp>\" to filter the series, \"top <n> [stat]\" to show the top n series or \"reset\" to show all series"
+		if m.err != nil {
+			help = fmt.Sprintf("Invalid input: %s", m.err.Error())
+		}
+
+		filter := m.storage.SeriesFilter(m.options.GraphID)
+		settings := fmt.Sprintf("Filter: %s, Top: %d %s", filter.Regexp, filter.TopN, filter.Stat)
+
+		err := m.Write(fmt.Sprintf("Selected series or command: %s \n\n%s\n%s\n\n%s", m.index, help, settings, strings.Join(m.rows, "\n")))
+		if err != nil {
+			return false
+		}
+	} else if m.options.Type == ModalTypeInterval {
+		help := "Select an index or enter a time range, e.g. \"now-90m\", \"now-2d/d to now/d\" or \"2020-10-01 14:00 to 16:30\""
+		if m.err != nil {
+			help = fmt.Sprintf("Invalid time range: %s", m.err.Error())
+		}
+		err := m.Write(fmt.Sprintf("Selected index or time range: %s \n\n%s\n\n%s", m.index, help, strings.Join(m.rows, "\n")))
+		if err != nil {
+			return false
+		}
 	} else {
 		if m.index == "" {
 			err := m.Write(fmt.Sprintf("Selected index: \n\n%s", strings.Join(m.rows, "\n")))
@@ -123,11 +212,15 @@ func (m *Modal) Show(options *ModalOptions) bool {
 	m.options = options
 	m.rows = nil
 	m.index = ""
+	m.err = nil
+	m.inspection = ""
+	m.message = ""
 	return m.show(true)
 }
 
 func (m *Modal) SelectIndex(index string) bool {
 	m.index = m.index + index
+	m.err = nil
 
 	if m.options.Type == ModalTypeExplore {
 		return m.show(true)
@@ -148,9 +241,38 @@ func (m *Modal) RemoveIndex() bool {
 	return m.show(false)
 }
 
+// AcceptsText returns true, when the modal accepts any text as input and not only the index of a row.
+func (m *Modal) AcceptsText() bool {
+	return m.options != nil && (m.options.Type == ModalTypeExplore || m.options.Type == ModalTypeInterval || m.options.Type == ModalTypeSeries)
+}
+
 func (m *Modal) Select() (ModalType, error) {
+	if m.options.Type == ModalTypeAlerts {
+		return m.options.Type, nil
+	}
+
+	if m.options.Type == ModalTypeInspect {
+		return m.options.Type, m.inspect()
+	}
+
+	if m.options.Type == ModalTypeSeries {
+		err := m.filterSeries()
+		if err != nil {
+			m.err = err
+			m.show(false)
+		}
+		return m.options.Type, err
+	}
+
 	if m.options.Type == ModalTypeExplore {
 		m.storage.Dashboard().Rows[0].Graphs[0].Queries[0].Query = m.index
+	} else if m.options.Type == ModalTypeInterval && !isIndex(m.index, len(intervals)) {
+		err := m.storage.ChangeInterval(m.index)
+		if err != nil {
+			m.err = err
+			m.show(false)
+			return m.options.Type, err
+		}
 	} else {
 		index, err := strconv.Atoi(m.index)
 		if err != nil {
@@ -186,3 +308,219 @@ func (m *Modal) Select() (ModalType, error) {
 
 	return m.options.Type, nil
 }
+
+// graphs returns all graphs of the active dashboard in the order in which they are rendered.
+func (m *Modal) graphs() []dashboard.Graph {
+	var graphs []dashboard.Graph
+	for _, row := range m.storage.Dashboard().Rows {
+		graphs = append(graphs, row.Graphs...)
+	}
+
+	return graphs
+}
+
+// inspect shows the inspection for the selected graph. If the inspection is already shown, it is exported to a file
+// in the temporary directory.
+func (m *Modal) inspect() error {
+	if m.inspection != "" {
+		path := filepath.Join(os.TempDir(), fmt.Sprintf("dash-inspect-%d.txt", time.Now().Unix()))
+		err := ioutil.WriteFile(path, []byte(m.inspection), 0644)
+		if err != nil {
+			m.message = fmt.Sprintf("Could not export the inspection: %s", err.Error())
+		} else {
+			m.message = fmt.Sprintf("Exported the inspection to %s", path)
+		}
+
+		m.show(false)
+		return errInspectionShown
+	}
+
+	graphs := m.graphs()
+	if !isIndex(m.index, len(graphs)) {
+		return fmt.Errorf("invalid index %s", m.index)
+	}
+
+	index, _ := strconv.Atoi(m.index)
+	graph := graphs[index]
+
+	ds, err := m.storage.GraphDatasource(graph)
+	if err != nil {
+		m.inspection = fmt.Sprintf("Could not inspect %s: %s", graph.Title, err.Error())
+		m.show(false)
+		return errInspectionShown
+	}
+
+	inspections, err := graph.Inspect(m.storage.Context(), ds, m.storage.Datasources, m.storage.VariableValues, m.storage.Interval.Start, m.storage.Interval.End)
+	if err != nil {
+		m.inspection = fmt.Sprintf("Could not inspect %s: %s", graph.Title, err.Error())
+	} else {
+		m.inspection = formatInspections(graph, m.graphDatasourceName(graph), inspections, m.storage.Location())
+	}
+
+	m.show(false)
+	return errInspectionShown
+}
+
+// filterSeries changes the filter for the series of the graph. The input is the index of a series, which should be
+// hidden or shown, "i<index>" to isolate a series, "/<regexp>" to filter the series by their label, "top <n> [stat]"
+// to show only the top n series by the given stat or "reset" to show all series again.
+func (m *Modal) filterSeries() error {
+	filter := m.storage.SeriesFilter(m.options.GraphID)
+	input := strings.TrimSpace(m.index)
+
+	switch {
+	case input == "reset":
+		filter = utils.SeriesFilter{}
+	case strings.HasPrefix(input, "/"):
+		if _, err := regexp.Compile(input[1:]); err != nil {
+			return err
+		}
+		filter.Regexp = input[1:]
+	case strings.HasPrefix(input, "top"):
+		fields := strings.Fields(input[3:])
+		if len(fields) == 0 || len(fields) > 2 {
+			return fmt.Errorf("expected \"top <n> [stat]\"")
+		}
+
+		topN, err := strconv.Atoi(fields[0])
+		if err != nil || topN < 0 {
+			return fmt.Errorf("invalid number of series %s", fields[0])
+		}
+
+		filter.TopN = topN
+		filter.Stat = ""
+		if len(fields) == 2 {
+			if !isStat(fields[1]) {
+				return fmt.Errorf("invalid stat %s", fields[1])
+			}
+			filter.Stat = fields[1]
+		}
+	case strings.HasPrefix(input, "i"):
+		if !isIndex(input[1:], len(m.options.Series)) {
+			return fmt.Errorf("invalid index %s", input[1:])
+		}
+
+		index, _ := strconv.Atoi(input[1:])
+		if filter.Isolated == m.options.Series[index] {
+			filter.Isolated = ""
+		} else {
+			filter.Isolated = m.options.Series[index]
+		}
+	default:
+		if !isIndex(input, len(m.options.Series)) {
+			return fmt.Errorf("invalid index %s", input)
+		}
+
+		index, _ := strconv.Atoi(input)
+		hidden := make(map[string]bool)
+		for label, value := range filter.Hidden {
+			hidden[label] = value
+		}
+		hidden[m.options.Series[index]] = !hidden[m.options.Series[index]]
+		filter.Hidden = hidden
+	}
+
+	m.storage.SetSeriesFilter(m.options.GraphID, filter)
+	return nil
+}
+
+// graphDatasourceName returns the name of the datasource, which is used for the queries of a graph without their own
+// datasource.
+func (m *Modal) graphDatasourceName(graph dashboard.Graph) string {
+	if graph.Datasource != "" {
+		return graph.Datasource
+	}
+
+	if _, ok := m.storage.Datasources[m.storage.Dashboard().DefaultDatasource]; ok {
+		return m.storage.Dashboard().DefaultDatasource
+	}
+
+	return m.storage.ActiveDatasource
+}
+
+// formatInspections returns the inspections of a graph as text.
+func formatInspections(graph dashboard.Graph, defaultDatasource string, inspections []dashboard.Inspection, location *time.Location) string {
+	var b strings.Builder
+	timeFormat := "2006-01-02 15:04:05"
+
+	fmt.Fprintf(&b, "Graph: %s (%s)\n", graph.Title, graph.Type)
+
+	for _, inspection := range inspections {
+		name := inspection.Datasource
+		if name == "" {
+			name = defaultDatasource
+		}
+
+		fmt.Fprintf(&b, "\nDatasource: %s", name)
+		if inspection.Offset != "" {
+			fmt.Fprintf(&b, " (offset %s)", inspection.Offset)
+		}
+		b.WriteString("\n")
+
+		for index, query := range inspection.Queries {
+			fmt.Fprintf(&b, "  Query: %s\n", query)
+			if inspection.Labels[index] != "" {
+				fmt.Fprintf(&b, "  Label: %s\n", inspection.Labels[index])
+			}
+		}
+
+		if !inspection.Trace.Start.IsZero() {
+			fmt.Fprintf(&b, "  Range: %s to %s\n", inspection.Trace.Start.In(location).Format(timeFormat), inspection.Trace.End.In(location).Format(timeFormat))
+		}
+		if inspection.Trace.Step != 0 {
+			fmt.Fprintf(&b, "  Step: %s\n", inspection.Trace.Step)
+		}
+		fmt.Fprintf(&b, "  Duration: %s\n", inspection.Duration.Round(time.Microsecond))
+
+		if inspection.Err != nil {
+			fmt.Fprintf(&b, "  Error: %s\n", inspection.Err.Error())
+			continue
+		}
+
+		if graph.Type == "table" || graph.Type == "logs" || graph.Type == "alertlist" {
+			fmt.Fprintf(&b, "  Results: %d\n", inspection.Results)
+			continue
+		}
+
+		var points int
+		for _, series := range inspection.Series {
+			points = points + len(series.Points)
+		}
+		fmt.Fprintf(&b, "  Series: %d, Points: %d\n", len(inspection.Series), points)
+
+		for _, series := range inspection.Series {
+			var nan int
+			for _, point := range series.Points {
+				if math.IsNaN(point) {
+					nan++
+				}
+			}
+
+			var labels []string
+			for key, value := range series.Labels {
+				labels = append(labels, fmt.Sprintf("%s=%q", key, value))
+			}
+			sort.Strings(labels)
+
+			fmt.Fprintf(&b, "    %s: %d points (%d NaN) {%s}\n", series.Label, len(series.Points), nan, strings.Join(labels, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// isIndex returns true, when the given value is a valid index for a list with the given length.
+func isIndex(value string, length int) bool {
+	index, err := strconv.Atoi(value)
+	return err == nil && index >= 0 && index < length
+}
+
+func isStat(stat string) bool {
+	for _, s := range seriesStats {
+		if s == stat {
+			return true
+		}
+	}
+
+	return false
+}
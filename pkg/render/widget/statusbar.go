@@ -3,6 +3,7 @@ package widget
 import (
 	"fmt"
 	"strings"
+	"sync"
 
 	"github.com/ricoberger/dash/pkg/render/utils"
 
@@ -13,6 +14,7 @@ import (
 type Statusbar struct {
 	*text.Text
 
+	mu      sync.Mutex
 	storage *utils.Storage
 }
 
@@ -22,12 +24,17 @@ func NewStatusbar(termWidth int, storage *utils.Storage) (*Statusbar, error) {
 		return nil, err
 	}
 
-	statusbar := &Statusbar{txt, storage}
+	statusbar := &Statusbar{Text: txt, storage: storage}
 	statusbar.Update(termWidth)
 	return statusbar, nil
 }
 
+// Update renders the statusbar for the current state. Update can be called concurrently, because the alerts are
+// updated while the graphs are loaded.
 func (s *Statusbar) Update(termWidth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	s.Reset()
 
 	var prefixedValues []string
@@ -45,7 +52,26 @@ func (s *Statusbar) Update(termWidth int) {
 	}
 	interval := fmt.Sprintf(" [F4] Interval: %s", s.storage.Interval.Interval)
 	refresh := fmt.Sprintf(" [F5] Refresh: %s ", s.storage.Refresh)
-	spaces := strings.Repeat(" ", termWidth-len(dashboard)-len(datasource)-len(variables)-len(interval)-len(refresh))
+
+	// The alerts are highlighted, when at least one alert is pending or firing, so that they are noticed also when dash
+	// is running in the background.
+	firing, pending := s.storage.Alerts.Count()
+	alerts := fmt.Sprintf(" [F6] Alerts: %d firing, %d pending ", firing, pending)
+	alertsColor := cell.ColorBlue
+	if firing > 0 {
+		alertsColor = cell.ColorRed
+	} else if pending > 0 {
+		alertsColor = cell.ColorYellow
+	}
+
+	// Custom time ranges can be much longer then the predefined intervals, so that we have to ensure that the number of
+	// spaces is not negative.
+	spacesCount := termWidth - len(dashboard) - len(datasource) - len(variables) - len(interval) - len(refresh) - len(alerts)
+	if spacesCount < 0 {
+		spacesCount = 0
+	}
+	spaces := strings.Repeat(" ", spacesCount)
 
 	s.Write(dashboard+datasource+variables+spaces+interval+refresh, text.WriteCellOpts(cell.BgColor(cell.ColorBlue), cell.FgColor(cell.ColorBlack)))
+	s.Write(alerts, text.WriteCellOpts(cell.BgColor(alertsColor), cell.FgColor(cell.ColorBlack)))
 }